@@ -1,7 +1,9 @@
 package app
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
@@ -16,11 +18,14 @@ import (
 	"github.com/jetstack/cni-migration/pkg"
 	"github.com/jetstack/cni-migration/pkg/cleanup"
 	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/ledger"
 	"github.com/jetstack/cni-migration/pkg/migrate"
 	"github.com/jetstack/cni-migration/pkg/preflight"
 	"github.com/jetstack/cni-migration/pkg/prepare"
 	"github.com/jetstack/cni-migration/pkg/priority"
 	"github.com/jetstack/cni-migration/pkg/roll"
+	"github.com/jetstack/cni-migration/pkg/rollback"
+	"github.com/jetstack/cni-migration/pkg/util"
 )
 
 type NewFunc func(context.Context, *config.Config) pkg.Step
@@ -32,6 +37,13 @@ type Options struct {
 	LogLevel   string
 	ConfigPath string
 
+	Interactive bool
+	PauseAfter  int
+
+	ResumePartial string
+
+	AllowPDBViolations bool
+
 	StepAll bool
 
 	//0
@@ -41,19 +53,26 @@ type Options struct {
 	StepPrepare bool
 
 	// 2
-	StepRollNodes    []string
-	StepRollAllNodes bool
+	StepRollNodes        []string
+	StepRollNodeSelector string
+	StepRollAllNodes     bool
 
 	// 3
-	StepChangeCNIPriority    []string
-	StepChangeCNIAllPriority bool
+	StepChangeCNIPriority             []string
+	StepChangeCNIPriorityNodeSelector string
+	StepChangeCNIAllPriority          bool
 
 	// 4
-	StepMigrateNodes    []string
-	StepMigrateAllNodes bool
+	StepMigrateNodes        []string
+	StepMigrateNodeSelector string
+	StepMigrateAllNodes     bool
 
 	// 5
 	StepCleanUp bool
+
+	RollbackNodes        []string
+	RollbackNodeSelector string
+	RollbackAllNodes     bool
 }
 
 const (
@@ -67,8 +86,20 @@ const (
   # Perform a migration only the first 2 steps
   cni-migration --no-dry-run -1 -2
 
+  # Migrate all nodes in a single zone
+  cni-migration --no-dry-run --step-migrate-node-selector topology.kubernetes.io/zone=europe-west1-b
+
+  # Migrate nodes one at a time, confirming after each node
+  cni-migration --no-dry-run --step-migrate-all-nodes --interactive
+
+  # Migrate at most 10 nodes, then stop
+  cni-migration --no-dry-run --step-migrate-all-nodes --pause-after 10
+
   # Perform a full live migration
-  cni-migration --no-dry-run --step-all`
+  cni-migration --no-dry-run --step-all
+
+  # Roll back all nodes to Canal
+  cni-migration --no-dry-run --rollback-all-nodes`
 )
 
 func NewRunCmd(ctx context.Context) *cobra.Command {
@@ -103,12 +134,55 @@ func NewRunCmd(ctx context.Context) *cobra.Command {
 				ctx = context.WithValue(ctx, migrate.ContextNodesKey, o.StepMigrateNodes)
 			}
 
+			if len(o.RollbackNodes) > 0 {
+				ctx = context.WithValue(ctx, rollback.ContextNodesKey, o.RollbackNodes)
+			}
+
+			ctx = context.WithValue(ctx, migrate.ContextResumeKey, o.ResumePartial)
+			ctx = context.WithValue(ctx, preflight.ContextAllowPDBViolationsKey, o.AllowPDBViolations)
+
+			if o.Interactive || o.PauseAfter > 0 {
+				ctx = context.WithValue(ctx, util.ContextPauseKey, &util.Pause{
+					Interactive: o.Interactive,
+					PauseAfter:  o.PauseAfter,
+					In:          bufio.NewReader(cmd.InOrStdin()),
+					Out:         cmd.OutOrStdout(),
+				})
+			}
+
 			config, err := config.New(o.ConfigPath, lvl, factory)
 			if err != nil {
 				return fmt.Errorf("failed to build config: %s", err)
 			}
 
+			for _, sel := range []struct {
+				key, flag, config string
+				names             []string
+				all               bool
+			}{
+				{roll.ContextNodeSelectorKey, o.StepRollNodeSelector, config.NodeSelectors.Roll, o.StepRollNodes, o.StepRollAllNodes},
+				{priority.ContextNodeSelectorKey, o.StepChangeCNIPriorityNodeSelector, config.NodeSelectors.Priority, o.StepChangeCNIPriority, o.StepChangeCNIAllPriority},
+				{migrate.ContextNodeSelectorKey, o.StepMigrateNodeSelector, config.NodeSelectors.Migrate, o.StepMigrateNodes, o.StepMigrateAllNodes},
+				{rollback.ContextNodeSelectorKey, o.RollbackNodeSelector, config.NodeSelectors.Rollback, o.RollbackNodes, o.RollbackAllNodes},
+			} {
+				// Flags take precedence over config. The config selector is
+				// ignored if nodes are explicitly selected by name, or all
+				// nodes are selected.
+				switch {
+				case len(sel.names) > 0, sel.all:
+				case len(sel.flag) > 0:
+					ctx = context.WithValue(ctx, sel.key, sel.flag)
+				case len(sel.config) > 0:
+					ctx = context.WithValue(ctx, sel.key, sel.config)
+				}
+			}
+
 			if err := run(ctx, config, o); err != nil {
+				if errors.Is(err, util.ErrPaused) {
+					config.Log.Infof("paused after %d nodes, re-run the same command to continue", o.PauseAfter)
+					return nil
+				}
+
 				config.Log.Error(err)
 				os.Exit(1)
 			}
@@ -118,7 +192,27 @@ func NewRunCmd(ctx context.Context) *cobra.Command {
 	}
 
 	nfs := new(cliflag.NamedFlagSets)
+	setUsage(cmd, nfs)
+
+	o.AddFlags(nfs.FlagSet("Option"))
+	factory = AddKubeFlags(cmd, nfs.FlagSet("Client"))
 
+	fs := cmd.Flags()
+	for _, f := range nfs.FlagSets {
+		fs.AddFlagSet(f)
+	}
+
+	cmd.AddCommand(NewStatusCmd(ctx))
+	cmd.AddCommand(NewConnectivityCmd(ctx))
+	cmd.AddCommand(NewConfigCmd(ctx))
+	cmd.AddCommand(NewRenderCmd(ctx))
+
+	return cmd
+}
+
+// setUsage sets the usage and help output of the command to print the named
+// flag sets.
+func setUsage(cmd *cobra.Command, nfs *cliflag.NamedFlagSets) {
 	// pretty output from kube-apiserver
 	usageFmt := "Usage:\n  %s\n\n"
 	cmd.SetUsageFunc(func(cmd *cobra.Command) error {
@@ -130,18 +224,19 @@ func NewRunCmd(ctx context.Context) *cobra.Command {
 	cmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		fmt.Fprintf(cmd.OutOrStdout(), "%s\n\n"+usageFmt, cmd.Long, cmd.UseLine())
 		fmt.Fprintf(cmd.OutOrStdout(), "Examples:%s\n", cmd.Example)
-		cliflag.PrintSections(cmd.OutOrStdout(), *nfs, -1)
-	})
-
-	o.AddFlags(nfs.FlagSet("Option"))
-	factory = AddKubeFlags(cmd, nfs.FlagSet("Client"))
 
-	fs := cmd.Flags()
-	for _, f := range nfs.FlagSets {
-		fs.AddFlagSet(f)
-	}
+		if cmd.HasAvailableSubCommands() {
+			fmt.Fprintf(cmd.OutOrStdout(), "\nAvailable Commands:\n")
+			for _, sub := range cmd.Commands() {
+				if sub.IsAvailableCommand() {
+					fmt.Fprintf(cmd.OutOrStdout(), "  %-12s %s\n", sub.Name(), sub.Short)
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "\n")
+		}
 
-	return cmd
+		cliflag.PrintSections(cmd.OutOrStdout(), *nfs, -1)
+	})
 }
 
 func run(ctx context.Context, config *config.Config, o *Options) error {
@@ -151,21 +246,60 @@ func run(ctx context.Context, config *config.Config, o *Options) error {
 		config.Log = config.Log.WithField("dry-run", "true")
 	}
 
-	var steps []pkg.Step
-	for _, f := range []NewFunc{
+	stepNames := []string{
+		preflight.StepName,
+		prepare.StepName,
+		roll.StepName,
+		priority.StepName,
+		migrate.StepName,
+		cleanup.StepName,
+	}
+
+	newFuncs := []NewFunc{
 		preflight.New,
 		prepare.New,
 		roll.New,
 		priority.New,
 		migrate.New,
 		cleanup.New,
-	} {
+	}
+
+	var steps []pkg.Step
+	for _, f := range newFuncs {
 		steps = append(steps, f(ctx, config))
 	}
 
+	l := ledger.New(ctx, config.Log, config.Client)
+	if err := l.LogInterrupted(); err != nil {
+		return err
+	}
+
+	// Each step is run with its own context, bounded by the step timeout.
+	runStep := func(i int) error {
+		return l.RecordStep(dryrun, stepNames[i], func() error {
+			return util.WithTimeout(ctx, config.Timeouts.Step, "step "+stepNames[i], func(ctx context.Context) error {
+				return newFuncs[i](ctx, config).Run(dryrun)
+			})
+		})
+	}
+
+	if len(o.RollbackNodes) > 0 || len(o.RollbackNodeSelector) > 0 || o.RollbackAllNodes {
+		if err := l.RecordStep(dryrun, rollback.StepName, func() error {
+			return util.WithTimeout(ctx, config.Timeouts.Step, "step "+rollback.StepName, func(ctx context.Context) error {
+				return rollback.New(ctx, config).Run(dryrun)
+			})
+		}); err != nil {
+			return err
+		}
+
+		config.Log.Info("rollback successful.")
+
+		return nil
+	}
+
 	if o.StepAll {
-		for _, s := range steps {
-			if err := s.Run(dryrun); err != nil {
+		for i := range steps {
+			if err := runStep(i); err != nil {
 				return err
 			}
 		}
@@ -179,11 +313,11 @@ func run(ctx context.Context, config *config.Config, o *Options) error {
 		o.StepPreflight,
 		o.StepPrepare,
 
-		(len(o.StepRollNodes) > 0 || o.StepRollAllNodes),
+		(len(o.StepRollNodes) > 0 || len(o.StepRollNodeSelector) > 0 || o.StepRollAllNodes),
 
-		(len(o.StepChangeCNIPriority) > 0 || o.StepChangeCNIAllPriority),
+		(len(o.StepChangeCNIPriority) > 0 || len(o.StepChangeCNIPriorityNodeSelector) > 0 || o.StepChangeCNIAllPriority),
 
-		(len(o.StepMigrateNodes) > 0 || o.StepMigrateAllNodes),
+		(len(o.StepMigrateNodes) > 0 || len(o.StepMigrateNodeSelector) > 0 || o.StepMigrateAllNodes),
 
 		o.StepCleanUp,
 	}
@@ -214,7 +348,7 @@ func run(ctx context.Context, config *config.Config, o *Options) error {
 				}
 			}
 
-			if err := steps[i].Run(dryrun); err != nil {
+			if err := runStep(i); err != nil {
 				return err
 			}
 
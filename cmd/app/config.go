@@ -0,0 +1,199 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	cliflag "k8s.io/component-base/cli/flag"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+type ConfigValidateOptions struct {
+	ConfigPath    string
+	PrintDefaults bool
+}
+
+type ConfigConvertOptions struct {
+	ConfigPath string
+	InPlace    bool
+}
+
+const (
+	configLong     = `  Manage cni-migration config files.`
+	configExamples = `
+  # Validate a config file
+  cni-migration config validate -c config.yaml
+
+  # Convert a config file to the current version
+  cni-migration config convert -c config.yaml --in-place`
+
+	configValidateLong = `  Validate a config file without connecting to the cluster. Checks that
+  required fields are set, label keys are valid, every path is a readable
+  manifest bundle, and every DaemonSet used by the migration is watched. All
+  problems found are printed.`
+	configValidateExamples = `
+  # Validate a config file
+  cni-migration config validate -c config.yaml
+
+  # Validate a config file, and print it with all defaults set
+  cni-migration config validate -c config.yaml --print-defaults`
+
+	configConvertLong = `  Convert a config file of an older version to the current version. Configs
+  without an apiVersion are converted from the unversioned config which
+  predates the first version. Comments are not preserved.`
+	configConvertExamples = `
+  # Print the converted config
+  cni-migration config convert -c config.yaml
+
+  # Overwrite the config with the converted config
+  cni-migration config convert -c config.yaml --in-place`
+)
+
+func NewConfigCmd(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "config",
+		Short:   "Manage cni-migration config files.",
+		Long:    configLong,
+		Example: configExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	nfs := new(cliflag.NamedFlagSets)
+	setUsage(cmd, nfs)
+
+	cmd.AddCommand(NewConfigValidateCmd(ctx))
+	cmd.AddCommand(NewConfigConvertCmd(ctx))
+
+	return cmd
+}
+
+func NewConfigValidateCmd(ctx context.Context) *cobra.Command {
+	o := new(ConfigValidateOptions)
+
+	cmd := &cobra.Command{
+		Use:     "validate",
+		Short:   "Validate a config file.",
+		Long:    configValidateLong,
+		Example: configValidateExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Read(o.ConfigPath)
+			if err != nil {
+				return err
+			}
+
+			if err := cfg.Validate(); err != nil {
+				errs := []error{err}
+				if agg, ok := err.(utilerrors.Aggregate); ok {
+					errs = agg.Errors()
+				}
+
+				for _, err := range errs {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s\n", err)
+				}
+
+				return fmt.Errorf("config %q is invalid, %d problems found", o.ConfigPath, len(errs))
+			}
+
+			if o.PrintDefaults {
+				data, err := yaml.Marshal(cfg)
+				if err != nil {
+					return fmt.Errorf("failed to marshal config: %s", err)
+				}
+
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "config %q is valid\n", o.ConfigPath)
+
+			return nil
+		},
+	}
+
+	nfs := new(cliflag.NamedFlagSets)
+	setUsage(cmd, nfs)
+
+	o.AddFlags(nfs.FlagSet("Option"))
+
+	fs := cmd.Flags()
+	for _, f := range nfs.FlagSets {
+		fs.AddFlagSet(f)
+	}
+
+	return cmd
+}
+
+func (o *ConfigValidateOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.ConfigPath, "config", "c", "config.yaml", "File path to the config path.")
+	fs.BoolVar(&o.PrintDefaults, "print-defaults", false, "Print the valid config with all defaults set.")
+}
+
+func NewConfigConvertCmd(ctx context.Context) *cobra.Command {
+	o := new(ConfigConvertOptions)
+
+	cmd := &cobra.Command{
+		Use:     "convert",
+		Short:   "Convert a config file to the current version.",
+		Long:    configConvertLong,
+		Example: configConvertExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := ioutil.ReadFile(o.ConfigPath)
+			if err != nil {
+				return fmt.Errorf("failed to read config path %q: %s", o.ConfigPath, err)
+			}
+
+			converted, from, err := config.Convert(data)
+			if err != nil {
+				return fmt.Errorf("failed to convert config %q: %s", o.ConfigPath, err)
+			}
+
+			// Ensure the converted config is valid for the current version.
+			if err := yaml.UnmarshalStrict(converted, new(config.Config)); err != nil {
+				return fmt.Errorf("failed to unmarshal converted config %q: %s", o.ConfigPath, err)
+			}
+
+			if !o.InPlace {
+				_, err := cmd.OutOrStdout().Write(converted)
+				return err
+			}
+
+			if from == config.APIVersion {
+				fmt.Fprintf(cmd.OutOrStdout(), "config %q is already %s\n", o.ConfigPath, config.APIVersion)
+				return nil
+			}
+
+			if err := ioutil.WriteFile(o.ConfigPath, converted, 0644); err != nil {
+				return fmt.Errorf("failed to write config %q: %s", o.ConfigPath, err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "config %q converted to %s\n", o.ConfigPath, config.APIVersion)
+
+			return nil
+		},
+	}
+
+	nfs := new(cliflag.NamedFlagSets)
+	setUsage(cmd, nfs)
+
+	o.AddFlags(nfs.FlagSet("Option"))
+
+	fs := cmd.Flags()
+	for _, f := range nfs.FlagSets {
+		fs.AddFlagSet(f)
+	}
+
+	return cmd
+}
+
+func (o *ConfigConvertOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.ConfigPath, "config", "c", "config.yaml", "File path to the config path.")
+	fs.BoolVar(&o.InPlace, "in-place", false, "Overwrite the config file with the converted config, rather than printing it.")
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	cliflag "k8s.io/component-base/cli/flag"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/util"
+)
+
+type ConnectivityOptions struct {
+	ConfigPath string
+	Output     string
+	ExportPath string
+}
+
+const (
+	connectivityLong = `  Probe connectivity from every knet-stress pod to a sample of other
+  knet-stress pods, and print a report of all failing probes, summarised by
+  node pair and by migration phase pair. Each endpoint is annotated with the
+  CNIs attached and the migration phase of its node. Probes which have not
+  finished within knetStress.timeout fail.`
+	connectivityExamples = `
+  # Print the failing node pairs and phase pairs
+  cni-migration connectivity
+
+  # Export the full connectivity matrix as YAML
+  cni-migration connectivity --export report.yaml`
+)
+
+func NewConnectivityCmd(ctx context.Context) *cobra.Command {
+	var factory cmdutil.Factory
+
+	o := new(ConnectivityOptions)
+
+	cmd := &cobra.Command{
+		Use:     "connectivity",
+		Short:   "Print a connectivity report of all knet-stress pods.",
+		Long:    connectivityLong,
+		Example: connectivityExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch o.Output {
+			case "table", "json", "yaml":
+			default:
+				return fmt.Errorf("unsupported --output %q, must be one of [table|json|yaml]", o.Output)
+			}
+
+			config, err := config.New(o.ConfigPath, logrus.InfoLevel, factory)
+			if err != nil {
+				return fmt.Errorf("failed to build config: %s", err)
+			}
+
+			// Probes still running after the knet-stress timeout fail, rather
+			// than hanging the command.
+			ctx, cancel := context.WithTimeout(ctx, config.KnetStress.Timeout)
+			defer cancel()
+
+			matrix, err := util.New(ctx, config.Log, config).ProbeKnetStress(ctx, 0)
+			if err != nil {
+				return fmt.Errorf("failed to probe knet-stress connectivity: %s", err)
+			}
+
+			report := matrix.Report()
+
+			if len(o.ExportPath) > 0 {
+				if err := report.WriteFile(o.ExportPath); err != nil {
+					return fmt.Errorf("failed to export connectivity report: %s", err)
+				}
+			}
+
+			return report.Write(cmd.OutOrStdout(), o.Output)
+		},
+	}
+
+	nfs := new(cliflag.NamedFlagSets)
+	setUsage(cmd, nfs)
+
+	o.AddFlags(nfs.FlagSet("Option"))
+	factory = AddKubeFlags(cmd, nfs.FlagSet("Client"))
+
+	fs := cmd.Flags()
+	for _, f := range nfs.FlagSets {
+		fs.AddFlagSet(f)
+	}
+
+	return cmd
+}
+
+func (o *ConnectivityOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.ConfigPath, "config", "c", "config.yaml", "File path to the config path.")
+	fs.StringVarP(&o.Output, "output", "o", "table", "Output format [table|json|yaml].")
+	fs.StringVar(&o.ExportPath, "export", "", "Optional file path to export the full connectivity report to, as YAML with a .yaml extension, otherwise JSON.")
+}
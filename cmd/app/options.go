@@ -11,24 +11,40 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/klog"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/jetstack/cni-migration/pkg/migrate"
 )
 
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&o.NoDryRun, "no-dry-run", false, "Run the CLI tool _not_ in dry run mode. This will attempt to migrate your cluster.")
 	fs.BoolVar(&o.StepAll, "step-all", false, "Run all steps. Cannot be used in conjunction with other step options.")
 	fs.BoolVarP(&o.StepPreflight, "step-preflight", "0", false, "[0] - Install knet-stress and ensure connectivity.")
+	fs.BoolVar(&o.AllowPDBViolations, "allow-pdb-violations", false, "[0] - Continue preflight when pod disruption budgets or unmanaged pods will block draining nodes.")
 	fs.BoolVarP(&o.StepPrepare, "step-prepare", "1", false, "[1] - Install required resource and prepare cluster.")
 
 	fs.BoolVarP(&o.StepRollAllNodes, "step-roll-all-nodes", "2", false, "[2] - Roll all nodes on the cluster to install both CNIs to workloads.")
 	fs.StringSliceVar(&o.StepRollNodes, "step-roll-nodes", nil, "[2] - Roll a list of nodes on the cluster to install both CNIs to workloads by node name.")
+	fs.StringVar(&o.StepRollNodeSelector, "step-roll-node-selector", "", "[2] - Roll all nodes matching a label selector to install both CNIs to workloads.")
 
 	fs.BoolVarP(&o.StepChangeCNIAllPriority, "step-change-cni-all-priority", "3", false, "[3] - Change the CNI priority to Cilium on all nodes.")
 	fs.StringSliceVar(&o.StepChangeCNIPriority, "step-change-cni-priority", nil, "[3] - Change the CNI priority to Cilium of a list of nodes by node name.")
+	fs.StringVar(&o.StepChangeCNIPriorityNodeSelector, "step-change-cni-priority-node-selector", "", "[3] - Change the CNI priority to Cilium of all nodes matching a label selector.")
 
 	fs.StringSliceVar(&o.StepMigrateNodes, "step-migrate-nodes", nil, "[4] - Migrate a list of nodes in the cluster by node name.")
+	fs.StringVar(&o.StepMigrateNodeSelector, "step-migrate-node-selector", "", "[4] - Migrate all nodes in the cluster matching a label selector.")
 	fs.BoolVarP(&o.StepMigrateAllNodes, "step-migrate-all-nodes", "4", false, "[4] - Migrate all nodes in the cluster, one by one.")
 
+	fs.StringVar(&o.ResumePartial, "resume-partial", migrate.ResumeFinish, "[4] - How to handle nodes left partially migrated by an interrupted run [finish|revert].")
+
 	fs.BoolVarP(&o.StepCleanUp, "step-clean-up", "5", false, "[5] - Clean up migration resources.")
+
+	fs.BoolVar(&o.RollbackAllNodes, "rollback-all-nodes", false, "Roll back all nodes to use Canal, then restore the canal DaemonSet. Cannot be used in conjunction with step options.")
+	fs.StringSliceVar(&o.RollbackNodes, "rollback-nodes", nil, "Roll back a list of nodes to use Canal by node name. Cannot be used in conjunction with step options.")
+	fs.StringVar(&o.RollbackNodeSelector, "rollback-node-selector", "", "Roll back all nodes matching a label selector to use Canal. Cannot be used in conjunction with step options.")
+
+	fs.BoolVar(&o.Interactive, "interactive", false, "After each batch of nodes in steps 2, 3 and 4, print a summary and prompt to continue, skip the next batch, retry or abort.")
+	fs.IntVar(&o.PauseAfter, "pause-after", 0, "Stop the run after processing this many nodes in steps 2, 3 and 4. Zero never stops.")
+
 	fs.StringVarP(&o.LogLevel, "log-level", "v", "debug", "Set logging level [debug|info|warn|error|fatal]")
 	fs.StringVarP(&o.ConfigPath, "config", "c", "config.yaml", "File path to the config path.")
 }
@@ -53,22 +69,64 @@ func AddKubeFlags(cmd *cobra.Command, fs *pflag.FlagSet) cmdutil.Factory {
 }
 
 func (o *Options) Validate() error {
-	if o.StepMigrateAllNodes && len(o.StepMigrateNodes) > 0 {
-		return errors.New("cannot enable both --step-migrate-all-nodes, as well as --step-migrate-nodes")
+	for _, step := range []struct {
+		all      bool
+		names    []string
+		selector string
+		flags    string
+	}{
+		{o.StepRollAllNodes, o.StepRollNodes, o.StepRollNodeSelector,
+			"--step-roll-all-nodes, --step-roll-nodes and --step-roll-node-selector"},
+		{o.StepChangeCNIAllPriority, o.StepChangeCNIPriority, o.StepChangeCNIPriorityNodeSelector,
+			"--step-change-cni-all-priority, --step-change-cni-priority and --step-change-cni-priority-node-selector"},
+		{o.StepMigrateAllNodes, o.StepMigrateNodes, o.StepMigrateNodeSelector,
+			"--step-migrate-all-nodes, --step-migrate-nodes and --step-migrate-node-selector"},
+		{o.RollbackAllNodes, o.RollbackNodes, o.RollbackNodeSelector,
+			"--rollback-all-nodes, --rollback-nodes and --rollback-node-selector"},
+	} {
+		var enabled int
+		for _, b := range []bool{step.all, len(step.names) > 0, len(step.selector) > 0} {
+			if b {
+				enabled++
+			}
+		}
+
+		if enabled > 1 {
+			return fmt.Errorf("only one of %s may be enabled", step.flags)
+		}
+	}
+
+	if o.RollbackAllNodes || len(o.RollbackNodes) > 0 || len(o.RollbackNodeSelector) > 0 {
+		for _, b := range []bool{
+			o.StepAll, o.StepPreflight, o.StepPrepare,
+			o.StepRollAllNodes, len(o.StepRollNodes) > 0, len(o.StepRollNodeSelector) > 0,
+			o.StepChangeCNIAllPriority, len(o.StepChangeCNIPriority) > 0, len(o.StepChangeCNIPriorityNodeSelector) > 0,
+			o.StepMigrateAllNodes, len(o.StepMigrateNodes) > 0, len(o.StepMigrateNodeSelector) > 0,
+			o.StepCleanUp,
+		} {
+			if b {
+				return errors.New("no step flags may be enabled with --rollback-all-nodes, --rollback-nodes or --rollback-node-selector")
+			}
+		}
 	}
 
-	if o.StepRollAllNodes && len(o.StepRollNodes) > 0 {
-		return errors.New("cannot enable both --step-roll-all-nodes, as well as --step-roll-nodes")
+	switch o.ResumePartial {
+	case migrate.ResumeFinish, migrate.ResumeRevert:
+	default:
+		return fmt.Errorf("unsupported --resume-partial %q, must be one of [%s|%s]",
+			o.ResumePartial, migrate.ResumeFinish, migrate.ResumeRevert)
 	}
 
-	if o.StepChangeCNIAllPriority && len(o.StepChangeCNIPriority) > 0 {
-		return errors.New("cannot enable both --step-change-all-cni-priority, as well as --step-change-cni-priority")
+	if o.PauseAfter < 0 {
+		return errors.New("--pause-after must not be negative")
 	}
 
 	if o.StepAll {
 		switch o.StepAll {
 		case o.StepPreflight, o.StepPrepare, o.StepRollAllNodes,
-			len(o.StepMigrateNodes) > 0, o.StepMigrateAllNodes, o.StepCleanUp:
+			len(o.StepMigrateNodes) > 0, o.StepMigrateAllNodes, o.StepCleanUp,
+			len(o.StepRollNodeSelector) > 0, len(o.StepChangeCNIPriorityNodeSelector) > 0,
+			len(o.StepMigrateNodeSelector) > 0:
 
 			return errors.New("no other step flags may be enabled with --step-all")
 		}
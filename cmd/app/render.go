@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+	cliflag "k8s.io/component-base/cli/flag"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+type RenderOptions struct {
+	ConfigPath string
+}
+
+const (
+	renderLong = `  Render the manifest bundles with the values of the config, apply the
+  patches of the config, and print every object as it would be applied to
+  the cluster. Bundles are any of cilium, multus, knet-stress and
+  network-policy. If no bundles are given, every bundle is rendered. Bundles
+  without a path set are the bundles embedded in the binary.`
+	renderExamples = `
+  # Render every bundle
+  cni-migration render
+
+  # Render the cilium and multus bundles only
+  cni-migration render cilium multus -c config.yaml`
+)
+
+func NewRenderCmd(ctx context.Context) *cobra.Command {
+	o := new(RenderOptions)
+
+	cmd := &cobra.Command{
+		Use:     "render [bundle...]",
+		Short:   "Render the manifest bundles from the config.",
+		Long:    renderLong,
+		Example: renderExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Read(o.ConfigPath)
+			if err != nil {
+				return err
+			}
+
+			paths := map[string]string{
+				"cilium":         cfg.Paths.Cilium,
+				"multus":         cfg.Paths.Multus,
+				"knet-stress":    cfg.Paths.KnetStress,
+				"network-policy": cfg.Paths.NetworkPolicy,
+			}
+
+			bundles := args
+			if len(bundles) == 0 {
+				bundles = []string{"cilium", "multus", "knet-stress", "network-policy"}
+			}
+
+			for _, bundle := range bundles {
+				path, ok := paths[bundle]
+				if !ok {
+					return fmt.Errorf("unknown bundle %q, must be one of [cilium|multus|knet-stress|network-policy]", bundle)
+				}
+
+				objs, err := cfg.ReadBundle(path)
+				if err != nil {
+					return err
+				}
+
+				for _, obj := range objs {
+					data, err := yaml.Marshal(obj.Object)
+					if err != nil {
+						return fmt.Errorf("failed to marshal %s: %s", config.PatchKey(obj), err)
+					}
+
+					fmt.Fprintf(cmd.OutOrStdout(), "---\n# Source: %s\n", path)
+					if _, err := cmd.OutOrStdout().Write(data); err != nil {
+						return err
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+
+	nfs := new(cliflag.NamedFlagSets)
+	setUsage(cmd, nfs)
+
+	o.AddFlags(nfs.FlagSet("Option"))
+
+	fs := cmd.Flags()
+	for _, f := range nfs.FlagSets {
+		fs.AddFlagSet(f)
+	}
+
+	return cmd
+}
+
+func (o *RenderOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.ConfigPath, "config", "c", "config.yaml", "File path to the config path.")
+}
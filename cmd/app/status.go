@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+	cliflag "k8s.io/component-base/cli/flag"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/status"
+)
+
+type StatusOptions struct {
+	ConfigPath string
+	Output     string
+}
+
+const (
+	statusLong = `  Print the migration phase of every node in the cluster, computed from the node
+  labels and taints, along with the state of the watched DaemonSets.`
+	statusExamples = `
+  # Print the status of the migration as a table
+  cni-migration status
+
+  # Print the status of the migration as JSON
+  cni-migration status -o json`
+)
+
+func NewStatusCmd(ctx context.Context) *cobra.Command {
+	var factory cmdutil.Factory
+
+	o := new(StatusOptions)
+
+	cmd := &cobra.Command{
+		Use:     "status",
+		Short:   "Print the migration phase of every node in the cluster.",
+		Long:    statusLong,
+		Example: statusExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch o.Output {
+			case "table", "json", "yaml":
+			default:
+				return fmt.Errorf("unsupported --output %q, must be one of [table|json|yaml]", o.Output)
+			}
+
+			config, err := config.New(o.ConfigPath, logrus.InfoLevel, factory)
+			if err != nil {
+				return fmt.Errorf("failed to build config: %s", err)
+			}
+
+			st, err := status.Get(ctx, config)
+			if err != nil {
+				return fmt.Errorf("failed to get migration status: %s", err)
+			}
+
+			return printStatus(cmd.OutOrStdout(), o.Output, st)
+		},
+	}
+
+	nfs := new(cliflag.NamedFlagSets)
+	setUsage(cmd, nfs)
+
+	o.AddFlags(nfs.FlagSet("Option"))
+	factory = AddKubeFlags(cmd, nfs.FlagSet("Client"))
+
+	fs := cmd.Flags()
+	for _, f := range nfs.FlagSets {
+		fs.AddFlagSet(f)
+	}
+
+	return cmd
+}
+
+func (o *StatusOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.ConfigPath, "config", "c", "config.yaml", "File path to the config path.")
+	fs.StringVarP(&o.Output, "output", "o", "table", "Output format [table|json|yaml].")
+}
+
+func printStatus(w io.Writer, output string, st *status.Status) error {
+	switch output {
+	case "json":
+		data, err := json.MarshalIndent(st, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+
+	case "yaml":
+		data, err := yaml.Marshal(st)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "NODE\tPHASE\tCORDONED\tTAINTED\tDAEMONSETS")
+	for _, n := range st.Nodes {
+		daemonsets := strings.Join(n.DaemonSets, ",")
+		if len(daemonsets) == 0 {
+			daemonsets = "<none>"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%t\t%s\n", n.Name, n.Phase, n.Cordoned, n.Tainted, daemonsets)
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "DAEMONSET\tDESIRED\tREADY\tUP-TO-DATE")
+	for _, ds := range st.DaemonSets {
+		if !ds.Found {
+			fmt.Fprintf(tw, "%s/%s\t<not found>\t\t\n", ds.Namespace, ds.Name)
+			continue
+		}
+		fmt.Fprintf(tw, "%s/%s\t%d\t%d\t%d\n", ds.Namespace, ds.Name, ds.Desired, ds.Ready, ds.Updated)
+	}
+
+	return tw.Flush()
+}
@@ -3,7 +3,7 @@ module github.com/jetstack/cni-migration
 go 1.14
 
 require (
-	github.com/evanphx/json-patch v4.5.0+incompatible // indirect
+	github.com/evanphx/json-patch v4.5.0+incompatible
 	github.com/imdario/mergo v0.3.9 // indirect
 	github.com/pkg/errors v0.9.0 // indirect
 	github.com/sirupsen/logrus v1.5.0
@@ -0,0 +1,64 @@
+// gen-bundles generates the Go source of the manifest bundles embedded in the
+// cni-migration binary.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		pkg    = flag.String("package", "manifest", "Package name of the generated file.")
+		output = flag.String("o", "zz_generated.bundles.go", "File path to write the generated file to.")
+	)
+	flag.Parse()
+
+	if err := run(*pkg, *output, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(pkg, output string, paths []string) error {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by hack/gen-bundles. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+	fmt.Fprintf(buf, "// embedded are the manifest bundles embedded in the binary, keyed by file\n// name.\n")
+	fmt.Fprintf(buf, "var embedded = map[string]string{\n")
+
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(buf, "%q: %s,\n", filepath.Base(path), literal(string(data)))
+	}
+
+	fmt.Fprintf(buf, "}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format generated source: %s", err)
+	}
+
+	return ioutil.WriteFile(output, src, 0644)
+}
+
+// literal returns s as a Go raw string literal. Backquotes, which can not
+// appear in a raw string, are concatenated as interpreted strings.
+func literal(s string) string {
+	parts := strings.Split(s, "`")
+	for i := range parts {
+		parts[i] = "`" + parts[i] + "`"
+	}
+
+	return strings.Join(parts, " + \"`\" + ")
+}
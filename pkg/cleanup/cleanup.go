@@ -4,14 +4,18 @@ import (
 	"context"
 
 	"github.com/sirupsen/logrus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/jetstack/cni-migration/pkg"
 	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/driver"
 	"github.com/jetstack/cni-migration/pkg/util"
 )
 
+const (
+	StepName = "5-cleanup"
+)
+
 var _ pkg.Step = &CleanUp{}
 
 type CleanUp struct {
@@ -19,18 +23,22 @@ type CleanUp struct {
 	log *logrus.Entry
 
 	config  *config.Config
-	client  *kubernetes.Clientset
+	client  kubernetes.Interface
 	factory *util.Factory
+	source  driver.CNIDriver
+	target  driver.CNIDriver
 }
 
 func New(ctx context.Context, config *config.Config) pkg.Step {
-	log := config.Log.WithField("step", "5-cleanup")
+	log := config.Log.WithField("step", StepName)
 	return &CleanUp{
 		log:     log,
 		ctx:     ctx,
 		config:  config,
 		client:  config.Client,
-		factory: util.New(ctx, log, config.Client),
+		factory: util.New(ctx, log, config),
+		source:  driver.Source(ctx, log, config),
+		target:  driver.Target(ctx, log, config),
 	}
 }
 
@@ -42,13 +50,11 @@ func (c *CleanUp) Ready() (bool, error) {
 		return !cleanUpResources, err
 	}
 
-	ds, err := c.client.AppsV1().DaemonSets("kube-system").Get(c.ctx, "cilium-migrated", metav1.GetOptions{})
-	if err != nil {
-		return false, err
-	}
-
-	if _, ok := ds.Spec.Template.Spec.NodeSelector[c.config.Labels.Cilium]; ok {
-		return false, nil
+	for _, d := range []driver.CNIDriver{c.source, c.target} {
+		cleanedUp, err := d.CleanedUp()
+		if err != nil || !cleanedUp {
+			return false, err
+		}
 	}
 
 	c.log.Info("step 5 ready")
@@ -59,42 +65,24 @@ func (c *CleanUp) Ready() (bool, error) {
 func (c *CleanUp) Run(dryrun bool) error {
 	c.log.Info("cleaning up...")
 
-	c.log.Info("removing node selector from cilium-migrated")
-	if !dryrun {
-		ds, err := c.client.AppsV1().DaemonSets("kube-system").Get(c.ctx, "cilium-migrated", metav1.GetOptions{})
-		if err != nil {
-			return err
-		}
-
-		delete(ds.Spec.Template.Spec.NodeSelector, c.config.Labels.Cilium)
-
-		_, err = c.client.AppsV1().DaemonSets("kube-system").Update(c.ctx, ds, metav1.UpdateOptions{})
-		if err != nil {
-			return err
-		}
+	c.log.Infof("cleaning up %s", c.target.Name())
+	if err := c.target.CleanUp(dryrun); err != nil {
+		return err
 	}
 
 	c.log.Infof("deleting multus: %s", c.config.Paths.Multus)
-	if !dryrun {
-		if err := c.factory.DeleteResource(c.config.Paths.Multus, "kube-system"); err != nil {
-			return err
-		}
+	if err := c.factory.DeleteResource(dryrun, c.config.Paths.Multus, "kube-system"); err != nil {
+		return err
 	}
 
-	c.log.Info("deleting canal DaemonSet")
-	if !dryrun {
-		err := c.client.AppsV1().DaemonSets("kube-system").Delete(c.ctx, "canal", metav1.DeleteOptions{})
-		if err != nil {
-			return err
-		}
+	c.log.Infof("cleaning up %s", c.source.Name())
+	if err := c.source.CleanUp(dryrun); err != nil {
+		return err
 	}
 
-	c.log.Info("deleting cilium DaemonSet")
-	if !dryrun {
-		err := c.client.AppsV1().DaemonSets("kube-system").Delete(c.ctx, "cilium", metav1.DeleteOptions{})
-		if err != nil {
-			return err
-		}
+	c.log.Infof("deleting network policy resources: %s", c.config.Paths.NetworkPolicy)
+	if err := c.factory.DeleteResource(dryrun, c.config.Paths.NetworkPolicy, c.config.Checks.NetworkPolicy.Namespace); err != nil {
+		return err
 	}
 
 	return nil
@@ -3,11 +3,18 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/jetstack/cni-migration/pkg/manifest"
 )
 
 type Labels struct {
@@ -22,10 +29,17 @@ type Labels struct {
 	Value string `yaml:"value"`
 }
 
+// Paths are the manifest bundles of the migration. Each is an optional
+// override of the bundle embedded in the binary, and may be a file, a
+// directory of manifests, or a URL.
 type Paths struct {
 	KnetStress string `yaml:"knet-stress"`
 	Cilium     string `yaml:"cilium"`
 	Multus     string `yaml:"multus"`
+
+	// NetworkPolicy is the manifest bundle deployed by the networkPolicy
+	// check.
+	NetworkPolicy string `yaml:"network-policy"`
 }
 
 type Resources struct {
@@ -34,28 +48,436 @@ type Resources struct {
 	StatefulSets map[string][]string `yaml:"statefulsets"`
 }
 
+// Drain holds options for evicting pods from nodes.
+type Drain struct {
+	// Timeout is the maximum time to wait for all pods to be evicted from a
+	// node. Zero means no timeout.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// GracePeriodSeconds overrides the termination grace period of evicted
+	// pods. If unset, each pod's own grace period is used.
+	GracePeriodSeconds *int64 `yaml:"gracePeriodSeconds"`
+}
+
+// Readiness holds options for waiting on resources to become ready.
+type Readiness struct {
+	// Timeout is the maximum time to wait for a resource to become ready. Zero
+	// means no timeout.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Resources overrides Timeout for individual resources, keyed by
+	// <kind>/<namespace>/<name>, e.g. daemonset/kube-system/cilium-migrated.
+	Resources map[string]time.Duration `yaml:"resources"`
+}
+
+// Batch holds options for processing nodes concurrently.
+type Batch struct {
+	// MaxUnavailable is the maximum number of nodes processed at once, either
+	// as an absolute number or a percentage of all nodes, e.g. "10%".
+	// Defaults to 1.
+	MaxUnavailable string `yaml:"maxUnavailable"`
+}
+
+// Ordering holds options for the order in which nodes are processed.
+type Ordering struct {
+	// ZoneLabel is the node label of each node's zone. Nodes in different
+	// zones are never processed at the same time.
+	ZoneLabel string `yaml:"zoneLabel"`
+
+	// NodePoolLabels are node labels of each node's pool. The first label
+	// present on a node is used. Nodes in different pools are never processed
+	// at the same time.
+	NodePoolLabels []string `yaml:"nodePoolLabels"`
+
+	// ControlPlaneLabels are node labels which mark control plane nodes. These
+	// nodes are processed last.
+	ControlPlaneLabels []string `yaml:"controlPlaneLabels"`
+
+	// Canary is the name of a node to always process first, alone.
+	Canary string `yaml:"canary"`
+}
+
+// NodeSelectors are label selectors of the nodes processed by each per node
+// step, used when no nodes are given on the command line.
+type NodeSelectors struct {
+	Roll     string `yaml:"roll"`
+	Priority string `yaml:"priority"`
+	Migrate  string `yaml:"migrate"`
+	Rollback string `yaml:"rollback"`
+}
+
+// KnetStress configures the knet-stress connectivity prober. The Container of
+// every knet-stress pod is exec'd into to run Command against a sample of
+// Destinations other knet-stress pods, where "{address}" in Command is
+// replaced with the destination pod IP and Port.
+type KnetStress struct {
+	Namespace   string        `yaml:"namespace"`
+	Selector    string        `yaml:"selector"`
+	Port        int32         `yaml:"port"`
+	Container   string        `yaml:"container"`
+	Command     []string      `yaml:"command"`
+	Concurrency int           `yaml:"concurrency"`
+	Timeout     time.Duration `yaml:"timeout"`
+	Interval    time.Duration `yaml:"interval"`
+
+	// Destinations is the number of other pods probed from each pod, sampled
+	// differently every round. Zero probes every other pod.
+	Destinations *int `yaml:"destinations"`
+
+	// ReportPath is an optional file path to write the connectivity report
+	// to when connectivity fails, as JSON, or YAML with a .yaml extension.
+	ReportPath string `yaml:"reportPath"`
+}
+
+// Checks configures the connectivity checks run alongside knet-stress. Enabled
+// is the list of check names to run, of the built-in checks clusterIP, dns,
+// nodePort and egress.
+// CheckNames are the names of the built-in connectivity checks, which may be
+// listed in Checks.Enabled.
+var CheckNames = []string{"clusterIP", "dns", "nodePort", "egress", "networkPolicy"}
+
+type Checks struct {
+	Enabled   []string      `yaml:"enabled"`
+	ClusterIP *ServiceCheck `yaml:"clusterIP"`
+	NodePort  *ServiceCheck `yaml:"nodePort"`
+	DNS       *DNSCheck     `yaml:"dns"`
+	Egress    *EgressCheck  `yaml:"egress"`
+
+	NetworkPolicy *NetworkPolicyCheck `yaml:"networkPolicy"`
+}
+
+// ServiceCheck is a Service to check connectivity to. The clusterIP check runs
+// the knet-stress command against the Service cluster IP, and the nodePort
+// check runs Command from a knet-stress pod on every node, where "{url}" in
+// Command is replaced with the URL of Path on the node port of the node.
+type ServiceCheck struct {
+	Namespace string   `yaml:"namespace"`
+	Name      string   `yaml:"name"`
+	Path      string   `yaml:"path"`
+	Command   []string `yaml:"command"`
+}
+
+// DNSCheck runs Command in a knet-stress pod on every node for each name in
+// Names, where "{name}" in Command is replaced with the name to look up.
+type DNSCheck struct {
+	Command []string `yaml:"command"`
+	Names   []string `yaml:"names"`
+}
+
+// EgressCheck runs Command in a knet-stress pod on every node, where "{url}"
+// in Command is replaced with URL.
+type EgressCheck struct {
+	Command []string `yaml:"command"`
+	URL     string   `yaml:"url"`
+}
+
+// NetworkPolicyCheck runs Command from the allowed and denied client pods of
+// the network policy bundle against a sample of Destinations server pods,
+// where "{address}" in Command is replaced with the server pod IP and Port.
+// Denied flows take the full timeout of Command to fail.
+type NetworkPolicyCheck struct {
+	Namespace string   `yaml:"namespace"`
+	Port      int32    `yaml:"port"`
+	Command   []string `yaml:"command"`
+
+	// Destinations is the number of server pods probed from each client pod.
+	// Zero probes every server pod.
+	Destinations *int `yaml:"destinations"`
+}
+
+// HealthCheck is a user defined check which gates every node operation,
+// alongside knet-stress. Exactly one of HTTP, Exec or Prometheus must be set.
+// Each attempt is bounded by Timeout, and a failed check is retried Retries
+// times.
+type HealthCheck struct {
+	Name    string        `yaml:"name"`
+	Timeout time.Duration `yaml:"timeout"`
+	Retries int           `yaml:"retries"`
+
+	HTTP       *HTTPHealthCheck       `yaml:"http"`
+	Exec       *ExecHealthCheck       `yaml:"exec"`
+	Prometheus *PrometheusHealthCheck `yaml:"prometheus"`
+}
+
+// HTTPHealthCheck requests Path from a Service through the API server service
+// proxy, succeeding on a 2xx response.
+type HTTPHealthCheck struct {
+	Namespace string `yaml:"namespace"`
+	Service   string `yaml:"service"`
+	Port      string `yaml:"port"`
+	Scheme    string `yaml:"scheme"`
+	Path      string `yaml:"path"`
+}
+
+// ExecHealthCheck runs Command in every running pod matching Selector,
+// succeeding if the command exits zero in all of them.
+type ExecHealthCheck struct {
+	Namespace string   `yaml:"namespace"`
+	Selector  string   `yaml:"selector"`
+	Container string   `yaml:"container"`
+	Command   []string `yaml:"command"`
+}
+
+// PrometheusHealthCheck runs an instant Query against a Prometheus API, either
+// at URL, or a Service through the API server service proxy. Every returned
+// sample must satisfy "value Operator Threshold".
+type PrometheusHealthCheck struct {
+	URL       string `yaml:"url"`
+	Namespace string `yaml:"namespace"`
+	Service   string `yaml:"service"`
+	Port      string `yaml:"port"`
+
+	Query     string  `yaml:"query"`
+	Operator  string  `yaml:"operator"`
+	Threshold float64 `yaml:"threshold"`
+}
+
+// Timeouts bounds how long each operation may take, as a whole. A zero
+// timeout is unbounded.
+type Timeouts struct {
+	// Step bounds each migration step.
+	Step time.Duration `yaml:"step"`
+
+	// Node bounds each operation on a single node in steps 2, 3 and 4,
+	// including its drain, which is bounded by Drain.Timeout, so must not be
+	// less than it.
+	Node time.Duration `yaml:"node"`
+
+	// Readiness bounds each wait for a resource to become ready. It is an
+	// alias of Readiness.Timeout, which per resource overrides still apply
+	// over.
+	Readiness time.Duration `yaml:"readiness"`
+
+	// Connectivity bounds each connectivity check, including knet-stress,
+	// the enabled checks and health checks, each of which is retried for up
+	// to KnetStress.Timeout, so must not be less than it.
+	Connectivity time.Duration `yaml:"connectivity"`
+}
+
+// Prerequisites configures the cluster prerequisite checks run by preflight
+// before any change is made. Enabled selects which of kernel, bpf, geneve,
+// images and kubernetesVersion are run.
+type Prerequisites struct {
+	Enabled []string `yaml:"enabled"`
+
+	// Namespace is created to run the short lived DaemonSets of the checks,
+	// and deleted once they are complete.
+	Namespace string `yaml:"namespace"`
+
+	// Image is run privileged on every node to check the kernel, BPF
+	// filesystem and Geneve reachability. It must contain a shell, nc,
+	// timeout and grep.
+	Image string `yaml:"image"`
+
+	MinKernelVersion     string `yaml:"minKernelVersion"`
+	MinKubernetesVersion string `yaml:"minKubernetesVersion"`
+	MaxKubernetesVersion string `yaml:"maxKubernetesVersion"`
+
+	// GenevePort is the UDP port checked between pairs of nodes.
+	GenevePort int32 `yaml:"genevePort"`
+
+	// GenevePeers is the number of other nodes every node sends to, and
+	// receives from, on the Geneve port, sampled differently on every run.
+	// Zero checks every pair of nodes.
+	GenevePeers *int `yaml:"genevePeers"`
+
+	// ImagePullSecrets are the <namespace>/<name> Secrets used to pull the
+	// images of the prerequisite checks. They are copied into Namespace for
+	// the duration of the checks.
+	ImagePullSecrets []string `yaml:"imagePullSecrets"`
+
+	// Timeout bounds all prerequisite checks.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// ReportPath, if set, is written with the prerequisites report. Written
+	// as YAML if the path ends with .yaml or .yml, otherwise as JSON.
+	ReportPath string `yaml:"reportPath"`
+}
+
+// Bundles holds the values the manifest bundles of Paths are rendered with.
+// Every bundle is a Go template, see Values.
+type Bundles struct {
+	// Registry, if set, replaces the registry of every image, e.g. to pull
+	// from a private registry mirror.
+	Registry string  `yaml:"registry"`
+	Images   *Images `yaml:"images"`
+
+	// TunnelMode is the Cilium encapsulation mode, either geneve or disabled
+	// for native routing.
+	TunnelMode string `yaml:"tunnelMode"`
+
+	// ClusterCIDR, if set, is the CIDR of all pods in the cluster. Cilium
+	// masquerades traffic to destinations outside of it.
+	ClusterCIDR string `yaml:"clusterCIDR"`
+
+	// CNIConfDir and CNIBinDir are the host directories of CNI configs and
+	// plugin binaries.
+	CNIConfDir string `yaml:"cniConfDir"`
+	CNIBinDir  string `yaml:"cniBinDir"`
+
+	Conflists *Conflists `yaml:"conflists"`
+}
+
+// Images are the images of the cilium, multus and knet-stress bundles.
+type Images struct {
+	Cilium         *Image `yaml:"cilium"`
+	CiliumOperator *Image `yaml:"ciliumOperator"`
+	Multus         *Image `yaml:"multus"`
+
+	// Probe is the image of the knet-stress probe container, which must
+	// provide the knetStress command and the commands of the checks.
+	Probe *Image `yaml:"probe"`
+}
+
+// Image is a container image repository and tag.
+type Image struct {
+	Repository string `yaml:"repository"`
+	Tag        string `yaml:"tag"`
+}
+
+// Conflists are the file names of the CNI configs written by the bundles. The
+// names of the source CNI config, and the config of migrated Cilium nodes,
+// are the configFile of cni.source and cni.target.
+type Conflists struct {
+	// Cilium is the config of Cilium running alongside the source CNI.
+	Cilium  string `yaml:"cilium"`
+	Multus  string `yaml:"multus"`
+	Flannel string `yaml:"flannel"`
+}
+
+// Patch is a patch of an object of the cilium, multus or knet-stress bundle,
+// applied after the bundle is rendered.
+type Patch struct {
+	// Type is either strategic, for a strategic merge patch, or json6902, for
+	// a list of JSON patch operations. Defaults to strategic.
+	Type string `yaml:"type"`
+
+	// Patch is the patch document, as YAML or JSON.
+	Patch string `yaml:"patch"`
+}
+
+const (
+	CNICanal   = "canal"
+	CNIFlannel = "flannel"
+	CNICalico  = "calico"
+	CNICilium  = "cilium"
+)
+
+// CNI selects the source and target CNI drivers of the migration.
+type CNI struct {
+	Source *Driver `yaml:"source"`
+	Target *Driver `yaml:"target"`
+}
+
+// Driver selects a CNI driver by name, optionally overriding its defaults.
+type Driver struct {
+	Name string `yaml:"name"`
+
+	// Namespace and DaemonSet of the CNI.
+	Namespace string `yaml:"namespace"`
+	DaemonSet string `yaml:"daemonset"`
+
+	// MigratedDaemonSet is the DaemonSet of a target CNI which runs on
+	// migrated nodes only.
+	MigratedDaemonSet string `yaml:"migratedDaemonset"`
+
+	// ConfigFile is the file name of the CNI config written to each node.
+	ConfigFile string `yaml:"configFile"`
+}
+
+// driverDefaults are the defaults of each supported CNI driver.
+var driverDefaults = map[string]Driver{
+	CNICanal: {
+		Namespace: "kube-system",
+		DaemonSet: "canal",
+		// Canal writes the CNI config of its calico plugin.
+		ConfigFile: "10-calico.conflist",
+	},
+	CNIFlannel: {
+		Namespace:  "kube-system",
+		DaemonSet:  "kube-flannel-ds",
+		ConfigFile: "10-flannel.conflist",
+	},
+	CNICalico: {
+		Namespace:  "kube-system",
+		DaemonSet:  "calico-node",
+		ConfigFile: "10-calico.conflist",
+	},
+	CNICilium: {
+		Namespace:         "kube-system",
+		DaemonSet:         "cilium",
+		MigratedDaemonSet: "cilium-migrated",
+		ConfigFile:        "00-cilium.conf",
+	},
+}
+
+// setDefaults sets the defaults of the driver by name for all unset options.
+func (d *Driver) setDefaults() {
+	def, ok := driverDefaults[d.Name]
+	if !ok {
+		return
+	}
+
+	if len(d.Namespace) == 0 {
+		d.Namespace = def.Namespace
+	}
+	if len(d.DaemonSet) == 0 {
+		d.DaemonSet = def.DaemonSet
+	}
+	if len(d.MigratedDaemonSet) == 0 {
+		d.MigratedDaemonSet = def.MigratedDaemonSet
+	}
+	if len(d.ConfigFile) == 0 {
+		d.ConfigFile = def.ConfigFile
+	}
+}
+
 type Config struct {
+	TypeMeta `yaml:",inline"`
+
 	*Labels            `yaml:"labels"`
 	*Paths             `yaml:"paths"`
-	PreflightResources *Resources `yaml:"preflightResources"`
-	WatchedResources   *Resources `yaml:"watchedResources"`
-	CleanUpResources   *Resources `yaml:"cleanUpResources"`
+	PreflightResources *Resources     `yaml:"preflightResources"`
+	WatchedResources   *Resources     `yaml:"watchedResources"`
+	CleanUpResources   *Resources     `yaml:"cleanUpResources"`
+	Drain              *Drain         `yaml:"drain"`
+	Readiness          *Readiness     `yaml:"readiness"`
+	CNI                *CNI           `yaml:"cni"`
+	Batch              *Batch         `yaml:"batch"`
+	Ordering           *Ordering      `yaml:"ordering"`
+	NodeSelectors      *NodeSelectors `yaml:"nodeSelectors"`
+	KnetStress         *KnetStress    `yaml:"knetStress"`
+	Checks             *Checks        `yaml:"checks"`
+	HealthChecks       []*HealthCheck `yaml:"healthChecks"`
+	Timeouts           *Timeouts      `yaml:"timeouts"`
+	Prerequisites      *Prerequisites `yaml:"prerequisites"`
+	Bundles            *Bundles       `yaml:"bundles"`
 
-	Client *kubernetes.Clientset
-	Log    *logrus.Entry
+	// Patches are applied to the objects of the cilium, multus and
+	// knet-stress bundles, keyed by <kind>/<namespace>/<name>, or
+	// <kind>/<name> for cluster scoped objects, e.g.
+	// daemonset/kube-system/cilium.
+	Patches map[string][]*Patch `yaml:"patches"`
+
+	Client        kubernetes.Interface `yaml:"-"`
+	RESTConfig    *rest.Config         `yaml:"-"`
+	DynamicClient dynamic.Interface    `yaml:"-"`
+	RESTMapper    meta.RESTMapper      `yaml:"-"`
+	Log           *logrus.Entry        `yaml:"-"`
+
+	// converted is true if the config was converted from an older version
+	// when read.
+	converted bool
 }
 
 func New(configPath string, logLevel logrus.Level, kubeFactory cmdutil.Factory) (*Config, error) {
-	yamlFile, err := ioutil.ReadFile(configPath)
+	config, err := Read(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config path %q: %s",
-			configPath, err)
+		return nil, err
 	}
 
-	config := new(Config)
-	if err := yaml.UnmarshalStrict(yamlFile, config); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config %q: %s",
-			configPath, err)
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %q: %s", configPath, err)
 	}
 
 	config.Client, err = kubeFactory.KubernetesClientSet()
@@ -63,9 +485,333 @@ func New(configPath string, logLevel logrus.Level, kubeFactory cmdutil.Factory)
 		return nil, fmt.Errorf("failed to build kubernetes client: %s", err)
 	}
 
+	config.RESTConfig, err = kubeFactory.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kubernetes rest config: %s", err)
+	}
+
+	config.DynamicClient, err = kubeFactory.DynamicClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kubernetes dynamic client: %s", err)
+	}
+
+	config.RESTMapper, err = kubeFactory.ToRESTMapper()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kubernetes rest mapper: %s", err)
+	}
+
 	logger := logrus.New()
 	logger.SetLevel(logLevel)
 	config.Log = logrus.NewEntry(logger)
 
+	if config.converted {
+		config.Log.Warnf("config %q is not %s, run \"cni-migration config convert -c %s --in-place\" to update it",
+			configPath, APIVersion, configPath)
+	}
+
+	return config, nil
+}
+
+// Read will read the config file at configPath, converting it to the current
+// version, and set defaults of all unset options. The config is not
+// validated, and no clients are built.
+func Read(configPath string) (*Config, error) {
+	yamlFile, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config path %q: %s",
+			configPath, err)
+	}
+
+	yamlFile, from, err := Convert(yamlFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert config %q: %s",
+			configPath, err)
+	}
+
+	config := new(Config)
+	if err := yaml.UnmarshalStrict(yamlFile, config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config %q: %s",
+			configPath, err)
+	}
+
+	config.converted = from != APIVersion
+
+	config.setDefaults()
+
 	return config, nil
 }
+
+// setDefaults sets the default of every unset option.
+func (c *Config) setDefaults() {
+	if c.Labels == nil {
+		c.Labels = new(Labels)
+	}
+	if c.Paths == nil {
+		c.Paths = new(Paths)
+	}
+	if len(c.Paths.KnetStress) == 0 {
+		c.Paths.KnetStress = manifest.Embedded("knet-stress.yaml")
+	}
+	if len(c.Paths.Cilium) == 0 {
+		c.Paths.Cilium = manifest.Embedded("cilium.yaml")
+	}
+	if len(c.Paths.Multus) == 0 {
+		c.Paths.Multus = manifest.Embedded("multus.yaml")
+	}
+	if len(c.Paths.NetworkPolicy) == 0 {
+		c.Paths.NetworkPolicy = manifest.Embedded("network-policy.yaml")
+	}
+	if c.PreflightResources == nil {
+		c.PreflightResources = new(Resources)
+	}
+	if c.WatchedResources == nil {
+		c.WatchedResources = new(Resources)
+	}
+	if c.CleanUpResources == nil {
+		c.CleanUpResources = new(Resources)
+	}
+	if c.Drain == nil {
+		c.Drain = new(Drain)
+	}
+	if c.Readiness == nil {
+		c.Readiness = new(Readiness)
+	}
+	if c.Batch == nil {
+		c.Batch = new(Batch)
+	}
+	if c.Ordering == nil {
+		c.Ordering = new(Ordering)
+	}
+	if len(c.Ordering.ZoneLabel) == 0 {
+		c.Ordering.ZoneLabel = "topology.kubernetes.io/zone"
+	}
+	if c.Ordering.ControlPlaneLabels == nil {
+		c.Ordering.ControlPlaneLabels = []string{
+			"node-role.kubernetes.io/master",
+			"node-role.kubernetes.io/control-plane",
+		}
+	}
+	if c.Timeouts == nil {
+		c.Timeouts = new(Timeouts)
+	}
+	if c.Readiness.Timeout == 0 {
+		c.Readiness.Timeout = c.Timeouts.Readiness
+	}
+	if c.NodeSelectors == nil {
+		c.NodeSelectors = new(NodeSelectors)
+	}
+	if c.KnetStress == nil {
+		c.KnetStress = new(KnetStress)
+	}
+	if len(c.KnetStress.Namespace) == 0 {
+		c.KnetStress.Namespace = "knet-stress"
+	}
+	if len(c.KnetStress.Selector) == 0 {
+		c.KnetStress.Selector = "app=knet-stress"
+	}
+	if c.KnetStress.Port == 0 {
+		c.KnetStress.Port = 6443
+	}
+	if len(c.KnetStress.Container) == 0 {
+		c.KnetStress.Container = "probe"
+	}
+	if len(c.KnetStress.Command) == 0 {
+		c.KnetStress.Command = []string{"wget", "-q", "-T", "5", "-O", "/dev/null", "http://{address}/metrics"}
+	}
+	if c.KnetStress.Concurrency <= 0 {
+		c.KnetStress.Concurrency = 10
+	}
+	if c.KnetStress.Destinations == nil {
+		destinations := 3
+		c.KnetStress.Destinations = &destinations
+	}
+	if c.KnetStress.Timeout == 0 {
+		c.KnetStress.Timeout = time.Minute * 5
+	}
+	if c.KnetStress.Interval == 0 {
+		c.KnetStress.Interval = time.Second * 5
+	}
+	if c.Checks == nil {
+		c.Checks = &Checks{
+			Enabled: []string{"clusterIP", "dns", "nodePort"},
+		}
+
+		// Flannel does not enforce NetworkPolicies, so they cannot be verified
+		// before nodes are migrated.
+		if c.CNI == nil || c.CNI.Source == nil || c.CNI.Source.Name != CNIFlannel {
+			c.Checks.Enabled = append(c.Checks.Enabled, "networkPolicy")
+		}
+	}
+	if c.Checks.ClusterIP == nil {
+		c.Checks.ClusterIP = new(ServiceCheck)
+	}
+	if len(c.Checks.ClusterIP.Namespace) == 0 {
+		c.Checks.ClusterIP.Namespace = c.KnetStress.Namespace
+	}
+	if len(c.Checks.ClusterIP.Name) == 0 {
+		c.Checks.ClusterIP.Name = "knet-stress"
+	}
+	if c.Checks.NodePort == nil {
+		c.Checks.NodePort = new(ServiceCheck)
+	}
+	if len(c.Checks.NodePort.Namespace) == 0 {
+		c.Checks.NodePort.Namespace = c.KnetStress.Namespace
+	}
+	if len(c.Checks.NodePort.Name) == 0 {
+		c.Checks.NodePort.Name = "knet-stress-nodeport"
+	}
+	if len(c.Checks.NodePort.Path) == 0 {
+		c.Checks.NodePort.Path = "/metrics"
+	}
+	if len(c.Checks.NodePort.Command) == 0 {
+		c.Checks.NodePort.Command = []string{"wget", "-q", "-T", "5", "-O", "/dev/null", "{url}"}
+	}
+	if c.Checks.DNS == nil {
+		c.Checks.DNS = new(DNSCheck)
+	}
+	if len(c.Checks.DNS.Command) == 0 {
+		c.Checks.DNS.Command = []string{"nslookup", "{name}"}
+	}
+	if len(c.Checks.DNS.Names) == 0 {
+		c.Checks.DNS.Names = []string{"kubernetes.default.svc.cluster.local"}
+	}
+	if c.Checks.Egress == nil {
+		c.Checks.Egress = new(EgressCheck)
+	}
+	if len(c.Checks.Egress.Command) == 0 {
+		c.Checks.Egress.Command = []string{"wget", "-q", "-T", "5", "-O", "/dev/null", "{url}"}
+	}
+	if c.Checks.NetworkPolicy == nil {
+		c.Checks.NetworkPolicy = new(NetworkPolicyCheck)
+	}
+	if len(c.Checks.NetworkPolicy.Namespace) == 0 {
+		c.Checks.NetworkPolicy.Namespace = "cni-migration-netpol"
+	}
+	if c.Checks.NetworkPolicy.Port == 0 {
+		c.Checks.NetworkPolicy.Port = 8080
+	}
+	if len(c.Checks.NetworkPolicy.Command) == 0 {
+		c.Checks.NetworkPolicy.Command = []string{"wget", "-q", "-T", "2", "-O", "/dev/null", "http://{address}/"}
+	}
+	if c.Checks.NetworkPolicy.Destinations == nil {
+		destinations := 3
+		c.Checks.NetworkPolicy.Destinations = &destinations
+	}
+	if c.Prerequisites == nil {
+		c.Prerequisites = &Prerequisites{
+			Enabled: []string{"kernel", "bpf", "geneve", "images", "kubernetesVersion"},
+		}
+	}
+	if len(c.Prerequisites.Namespace) == 0 {
+		c.Prerequisites.Namespace = "cni-migration-prerequisites"
+	}
+	if len(c.Prerequisites.Image) == 0 {
+		c.Prerequisites.Image = "busybox:1.32"
+	}
+	if len(c.Prerequisites.MinKernelVersion) == 0 {
+		c.Prerequisites.MinKernelVersion = "4.9.17"
+	}
+	if len(c.Prerequisites.MinKubernetesVersion) == 0 {
+		c.Prerequisites.MinKubernetesVersion = "1.11"
+	}
+	if len(c.Prerequisites.MaxKubernetesVersion) == 0 {
+		c.Prerequisites.MaxKubernetesVersion = "1.18"
+	}
+	if c.Prerequisites.GenevePort == 0 {
+		c.Prerequisites.GenevePort = 6081
+	}
+	if c.Prerequisites.GenevePeers == nil {
+		peers := 3
+		c.Prerequisites.GenevePeers = &peers
+	}
+	if c.Prerequisites.Timeout == 0 {
+		c.Prerequisites.Timeout = time.Minute * 10
+	}
+	if c.Bundles == nil {
+		c.Bundles = new(Bundles)
+	}
+	if c.Bundles.Images == nil {
+		c.Bundles.Images = new(Images)
+	}
+	c.Bundles.Images.Cilium = imageOrDefault(c.Bundles.Images.Cilium, "docker.io/cilium/cilium", "v1.7.4")
+	c.Bundles.Images.CiliumOperator = imageOrDefault(c.Bundles.Images.CiliumOperator, "docker.io/cilium/operator", "v1.7.4")
+	c.Bundles.Images.Multus = imageOrDefault(c.Bundles.Images.Multus, "gcr.io/jetstack-cre/multus", "v3.4.1-cni-bundle-1")
+	c.Bundles.Images.Probe = imageOrDefault(c.Bundles.Images.Probe, "busybox", "1.32")
+	if len(c.Bundles.TunnelMode) == 0 {
+		c.Bundles.TunnelMode = "geneve"
+	}
+	if len(c.Bundles.CNIConfDir) == 0 {
+		c.Bundles.CNIConfDir = "/etc/kubernetes/cni/net.d"
+	}
+	if len(c.Bundles.CNIBinDir) == 0 {
+		c.Bundles.CNIBinDir = "/opt/cni/bin"
+	}
+	if c.Bundles.Conflists == nil {
+		c.Bundles.Conflists = new(Conflists)
+	}
+	if len(c.Bundles.Conflists.Cilium) == 0 {
+		c.Bundles.Conflists.Cilium = "99-cilium.conf"
+	}
+	if len(c.Bundles.Conflists.Multus) == 0 {
+		c.Bundles.Conflists.Multus = "00-multus.conflist"
+	}
+	if len(c.Bundles.Conflists.Flannel) == 0 {
+		c.Bundles.Conflists.Flannel = "99-flannel.conflist"
+	}
+	if c.CNI == nil {
+		c.CNI = new(CNI)
+	}
+	if c.CNI.Source == nil {
+		c.CNI.Source = &Driver{Name: CNICanal}
+	}
+	if c.CNI.Target == nil {
+		c.CNI.Target = &Driver{Name: CNICilium}
+	}
+	c.CNI.Source.setDefaults()
+	c.CNI.Target.setDefaults()
+
+	for _, patches := range c.Patches {
+		for _, patch := range patches {
+			if patch != nil && len(patch.Type) == 0 {
+				patch.Type = manifest.PatchTypeStrategic
+			}
+		}
+	}
+
+	for i, check := range c.HealthChecks {
+		// Empty entries are rejected by validation.
+		if check == nil {
+			continue
+		}
+		if len(check.Name) == 0 {
+			check.Name = fmt.Sprintf("healthCheck[%d]", i)
+		}
+		if check.Timeout == 0 {
+			check.Timeout = time.Second * 10
+		}
+	}
+}
+
+// imageOrDefault sets the default repository and tag of the image if unset.
+func imageOrDefault(image *Image, repository, tag string) *Image {
+	if image == nil {
+		image = new(Image)
+	}
+	if len(image.Repository) == 0 {
+		image.Repository = repository
+	}
+	if len(image.Tag) == 0 {
+		image.Tag = tag
+	}
+
+	return image
+}
+
+// TimeoutFor returns the readiness timeout of the given resource.
+func (r *Readiness) TimeoutFor(kind, namespace, name string) time.Duration {
+	if timeout, ok := r.Resources[strings.ToLower(kind)+"/"+namespace+"/"+name]; ok {
+		return timeout
+	}
+
+	return r.Timeout
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDefaultChecks(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config *Config
+		want   []string
+	}{
+		{"default source", &Config{}, []string{"clusterIP", "dns", "nodePort", "networkPolicy"}},
+		{"canal", &Config{CNI: &CNI{Source: &Driver{Name: CNICanal}}}, []string{"clusterIP", "dns", "nodePort", "networkPolicy"}},
+		{"calico", &Config{CNI: &CNI{Source: &Driver{Name: CNICalico}}}, []string{"clusterIP", "dns", "nodePort", "networkPolicy"}},
+		{"flannel", &Config{CNI: &CNI{Source: &Driver{Name: CNIFlannel}}}, []string{"clusterIP", "dns", "nodePort"}},
+		{"set", &Config{Checks: &Checks{Enabled: []string{"dns"}}}, []string{"dns"}},
+		{"set empty", &Config{Checks: &Checks{Enabled: []string{}}}, []string{}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.setDefaults()
+
+			if got := test.config.Checks.Enabled; !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got enabled checks %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDefaultReadinessTimeout(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		readiness time.Duration
+		alias     time.Duration
+		want      time.Duration
+	}{
+		{"unset", 0, 0, 0},
+		{"readiness", time.Minute, 0, time.Minute},
+		{"alias", 0, time.Minute * 2, time.Minute * 2},
+		{"both", time.Minute, time.Minute * 2, time.Minute},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Config{
+				Readiness: &Readiness{Timeout: test.readiness},
+				Timeouts:  &Timeouts{Readiness: test.alias},
+			}
+			c.setDefaults()
+
+			if c.Readiness.Timeout != test.want {
+				t.Errorf("got readiness timeout %s, want %s", c.Readiness.Timeout, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/jetstack/cni-migration/pkg/manifest"
+)
+
+// ReadBundle will render and decode the manifest bundle at path. Objects of
+// the cilium, multus and knet-stress bundles are patched with Patches.
+func (c *Config) ReadBundle(path string) ([]*unstructured.Unstructured, error) {
+	objs, err := manifest.Read(path, c.Values())
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.isPatchedBundle(path) {
+		return objs, nil
+	}
+
+	for i, obj := range objs {
+		key := PatchKey(obj)
+		for j, patch := range c.Patches[key] {
+			if patch == nil {
+				continue
+			}
+
+			objs[i], err = manifest.Patch(objs[i], patch.Type, []byte(patch.Patch))
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply patch %d of %s to manifest %q: %s", j, key, path, err)
+			}
+		}
+	}
+
+	return objs, nil
+}
+
+// PatchKey returns the key of the object in Patches.
+func PatchKey(obj *unstructured.Unstructured) string {
+	kind := strings.ToLower(obj.GetKind())
+	if len(obj.GetNamespace()) == 0 {
+		return kind + "/" + obj.GetName()
+	}
+
+	return kind + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// isPatchedBundle returns true if the bundle at path is patched.
+func (c *Config) isPatchedBundle(path string) bool {
+	return path == c.Paths.Cilium || path == c.Paths.Multus || path == c.Paths.KnetStress
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/jetstack/cni-migration/pkg/manifest"
+)
+
+// TestRenderBaselineConfig ensures that the embedded bundles rendered with a
+// config which predates templating produce the same objects as the static
+// bundles of that time.
+func TestRenderBaselineConfig(t *testing.T) {
+	cfg, err := Read("testdata/baseline/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The baseline paths are relative to the repository root.
+	cfg.Paths.Cilium = manifest.Embedded("cilium.yaml")
+	cfg.Paths.Multus = manifest.Embedded("multus.yaml")
+
+	for _, test := range []struct {
+		name, path, baseline string
+	}{
+		{"cilium", cfg.Paths.Cilium, "testdata/baseline/cilium.yaml"},
+		{"multus", cfg.Paths.Multus, "testdata/baseline/multus.yaml"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := cfg.ReadBundle(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := os.Open(test.baseline)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			want, err := manifest.Decode(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("got %d objects, want %d", len(got), len(want))
+			}
+
+			for i := range want {
+				if !reflect.DeepEqual(got[i].Object, want[i].Object) {
+					t.Errorf("%s differs from baseline:\ngot:  %v\nwant: %v",
+						PatchKey(want[i]), got[i].Object, want[i].Object)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertUnversionedPinsCanalConfigFile(t *testing.T) {
+	for _, test := range []struct {
+		name, config, want string
+	}{
+		{"no cni", "labels: {}\n", unversionedCanalConfigFile},
+		{"canal source", "cni:\n  source:\n    name: canal\n", unversionedCanalConfigFile},
+		{"canal source with config file", "cni:\n  source:\n    name: canal\n    configFile: 10-canal.conflist\n", "10-canal.conflist"},
+		{"flannel source", "cni:\n  source:\n    name: flannel\n", "10-flannel.conflist"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data, _, err := Convert([]byte(test.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := new(Config)
+			if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+				t.Fatal(err)
+			}
+			cfg.setDefaults()
+
+			if got := cfg.CNI.Source.ConfigFile; got != test.want {
+				t.Errorf("got source config file %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,487 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/jetstack/cni-migration/pkg/manifest"
+)
+
+// Validate will check that the config is complete and consistent, returning
+// an aggregate of every problem found. Defaults must already be set.
+func (c *Config) Validate() error {
+	var errs []error
+	for _, fn := range []func() []error{
+		c.validateLabels,
+		c.validatePaths,
+		c.validateCNI,
+		c.validateWatchedResources,
+		c.validateDurations,
+		c.validatePorts,
+		c.validateKnetStress,
+		c.validateChecks,
+		c.validateHealthChecks,
+		c.validatePrerequisites,
+		c.validateBundles,
+		c.validatePatches,
+	} {
+		errs = append(errs, fn()...)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateLabels checks every label key is set and is a valid label key, and
+// the shared value is set and is a valid label value.
+func (c *Config) validateLabels() []error {
+	var errs []error
+
+	for _, label := range []struct {
+		field, key string
+	}{
+		{"canal-cilium", c.Labels.CanalCilium},
+		{"rolled", c.Labels.Rolled},
+		{"cni-priority-canal", c.Labels.CNIPriorityCanal},
+		{"cni-priority-cilium", c.Labels.CNIPriorityCilium},
+		{"cilium", c.Labels.Cilium},
+		{"migrated", c.Labels.Migrated},
+	} {
+		if len(label.key) == 0 {
+			errs = append(errs, fmt.Errorf("labels.%s: required", label.field))
+			continue
+		}
+
+		for _, msg := range validation.IsQualifiedName(label.key) {
+			errs = append(errs, fmt.Errorf("labels.%s: invalid label key %q: %s", label.field, label.key, msg))
+		}
+	}
+
+	if len(c.Labels.Value) == 0 {
+		errs = append(errs, fmt.Errorf("labels.value: required"))
+	}
+	for _, msg := range validation.IsValidLabelValue(c.Labels.Value) {
+		errs = append(errs, fmt.Errorf("labels.value: invalid label value %q: %s", c.Labels.Value, msg))
+	}
+
+	return errs
+}
+
+// validatePaths checks every required path is set, and every set path is a
+// manifest bundle of at least one object.
+func (c *Config) validatePaths() []error {
+	var networkPolicy bool
+	for _, name := range c.Checks.Enabled {
+		if name == "networkPolicy" {
+			networkPolicy = true
+		}
+	}
+
+	var errs []error
+	for _, path := range []struct {
+		field, path string
+		required    bool
+	}{
+		{"cilium", c.Paths.Cilium, true},
+		{"multus", c.Paths.Multus, true},
+		{"knet-stress", c.Paths.KnetStress, true},
+		{"network-policy", c.Paths.NetworkPolicy, networkPolicy},
+	} {
+		if len(path.path) == 0 {
+			if path.required {
+				errs = append(errs, fmt.Errorf("paths.%s: required", path.field))
+			}
+			continue
+		}
+
+		objs, err := c.ReadBundle(path.path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("paths.%s: %s", path.field, err))
+			continue
+		}
+
+		if len(objs) == 0 {
+			errs = append(errs, fmt.Errorf("paths.%s: manifest %q contains no objects", path.field, path.path))
+		}
+	}
+
+	return errs
+}
+
+func (c *Config) validateCNI() []error {
+	var errs []error
+
+	switch c.CNI.Source.Name {
+	case CNICanal, CNIFlannel, CNICalico:
+	default:
+		errs = append(errs, fmt.Errorf("cni.source.name: unsupported source CNI %q, must be one of [%s|%s|%s]",
+			c.CNI.Source.Name, CNICanal, CNIFlannel, CNICalico))
+	}
+
+	switch c.CNI.Target.Name {
+	case CNICilium:
+	default:
+		errs = append(errs, fmt.Errorf("cni.target.name: unsupported target CNI %q, must be one of [%s]",
+			c.CNI.Target.Name, CNICilium))
+	}
+
+	return errs
+}
+
+// validateWatchedResources checks that every DaemonSet waited on, or cleaned
+// up, by the migration is watched. These are the DaemonSets of the source and
+// target CNIs, and of the multus bundle.
+func (c *Config) validateWatchedResources() []error {
+	referenced := map[string]bool{
+		c.CNI.Source.Namespace + "/" + c.CNI.Source.DaemonSet: true,
+		c.CNI.Target.Namespace + "/" + c.CNI.Target.DaemonSet: true,
+	}
+	if len(c.CNI.Target.MigratedDaemonSet) > 0 {
+		referenced[c.CNI.Target.Namespace+"/"+c.CNI.Target.MigratedDaemonSet] = true
+	}
+
+	// Errors reading the bundle are reported by validatePaths.
+	if objs, err := c.ReadBundle(c.Paths.Multus); err == nil {
+		for _, obj := range objs {
+			if obj.GetKind() != "DaemonSet" {
+				continue
+			}
+
+			namespace := obj.GetNamespace()
+			if len(namespace) == 0 {
+				namespace = "kube-system"
+			}
+			referenced[namespace+"/"+obj.GetName()] = true
+		}
+	}
+
+	watched := make(map[string]bool)
+	for namespace, names := range c.WatchedResources.DaemonSets {
+		for _, name := range names {
+			watched[namespace+"/"+name] = true
+		}
+	}
+
+	var missing []string
+	for ds := range referenced {
+		if !watched[ds] {
+			missing = append(missing, ds)
+		}
+	}
+	sort.Strings(missing)
+
+	var errs []error
+	for _, ds := range missing {
+		errs = append(errs, fmt.Errorf("watchedResources.daemonsets: %s is used by the migration but is not watched", ds))
+	}
+
+	return errs
+}
+
+func (c *Config) validateDurations() []error {
+	var errs []error
+
+	for _, d := range []struct {
+		field    string
+		negative bool
+	}{
+		{"drain.timeout", c.Drain.Timeout < 0},
+		{"readiness.timeout", c.Readiness.Timeout < 0},
+		{"knetStress.timeout", c.KnetStress.Timeout < 0},
+		{"knetStress.interval", c.KnetStress.Interval < 0},
+		{"timeouts.step", c.Timeouts.Step < 0},
+		{"timeouts.node", c.Timeouts.Node < 0},
+		{"timeouts.readiness", c.Timeouts.Readiness < 0},
+		{"timeouts.connectivity", c.Timeouts.Connectivity < 0},
+		{"prerequisites.timeout", c.Prerequisites.Timeout < 0},
+	} {
+		if d.negative {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", d.field))
+		}
+	}
+
+	for key, timeout := range c.Readiness.Resources {
+		if timeout < 0 {
+			errs = append(errs, fmt.Errorf("readiness.resources.%s: must not be negative", key))
+		}
+	}
+
+	// timeouts.readiness is an alias of readiness.timeout, and is copied to it
+	// when only the alias is set.
+	if c.Timeouts.Readiness != 0 && c.Timeouts.Readiness != c.Readiness.Timeout {
+		errs = append(errs, fmt.Errorf("timeouts.readiness: %s conflicts with readiness.timeout (%s), set only one",
+			c.Timeouts.Readiness, c.Readiness.Timeout))
+	}
+
+	// An inner timeout longer than its outer timeout never takes effect.
+	for _, d := range []struct {
+		inner, outer       string
+		innerDur, outerDur time.Duration
+	}{
+		{"drain.timeout", "timeouts.node", c.Drain.Timeout, c.Timeouts.Node},
+		{"knetStress.timeout", "timeouts.connectivity", c.KnetStress.Timeout, c.Timeouts.Connectivity},
+	} {
+		if d.outerDur > 0 && d.innerDur > d.outerDur {
+			errs = append(errs, fmt.Errorf("%s: %s must not exceed %s (%s)",
+				d.inner, d.innerDur, d.outer, d.outerDur))
+		}
+	}
+
+	return errs
+}
+
+func (c *Config) validatePorts() []error {
+	var errs []error
+
+	for _, p := range []struct {
+		field string
+		port  int32
+	}{
+		{"knetStress.port", c.KnetStress.Port},
+		{"checks.networkPolicy.port", c.Checks.NetworkPolicy.Port},
+		{"prerequisites.genevePort", c.Prerequisites.GenevePort},
+	} {
+		for _, msg := range validation.IsValidPortNum(int(p.port)) {
+			errs = append(errs, fmt.Errorf("%s: %s", p.field, msg))
+		}
+	}
+
+	return errs
+}
+
+// validateKnetStress checks the knet-stress prober is able to probe.
+func (c *Config) validateKnetStress() []error {
+	var errs []error
+
+	if *c.KnetStress.Destinations < 0 {
+		errs = append(errs, fmt.Errorf("knetStress.destinations: must not be negative"))
+	}
+
+	if *c.Checks.NetworkPolicy.Destinations < 0 {
+		errs = append(errs, fmt.Errorf("checks.networkPolicy.destinations: must not be negative"))
+	}
+
+	return errs
+}
+
+// validateChecks checks every enabled connectivity check is a built-in check,
+// and is configured.
+func (c *Config) validateChecks() []error {
+	var errs []error
+
+	known := make(map[string]bool)
+	for _, name := range CheckNames {
+		known[name] = true
+	}
+
+	for _, name := range c.Checks.Enabled {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("checks.enabled: unknown check %q, must be one of [%s]",
+				name, strings.Join(CheckNames, "|")))
+		}
+
+		if name == "egress" && len(c.Checks.Egress.URL) == 0 {
+			errs = append(errs, fmt.Errorf("checks.egress.url: required when the egress check is enabled"))
+		}
+	}
+
+	return errs
+}
+
+// validateHealthChecks checks each health check is not empty, does not have
+// negative retries or timeout, and has exactly one of http, exec or
+// prometheus, and a supported operator.
+func (c *Config) validateHealthChecks() []error {
+	var errs []error
+
+	for i, check := range c.HealthChecks {
+		if check == nil {
+			errs = append(errs, fmt.Errorf("healthChecks[%d]: must not be empty", i))
+			continue
+		}
+
+		if check.Retries < 0 {
+			errs = append(errs, fmt.Errorf("healthChecks.%s.retries: must not be negative", check.Name))
+		}
+
+		if check.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("healthChecks.%s.timeout: must not be negative", check.Name))
+		}
+
+		var set int
+		for _, b := range []bool{check.HTTP != nil, check.Exec != nil, check.Prometheus != nil} {
+			if b {
+				set++
+			}
+		}
+		if set != 1 {
+			errs = append(errs, fmt.Errorf("healthChecks.%s: must have exactly one of http, exec or prometheus", check.Name))
+		}
+
+		if check.Prometheus != nil {
+			switch check.Prometheus.Operator {
+			case "<", "<=", ">", ">=", "==", "!=":
+			default:
+				errs = append(errs, fmt.Errorf("healthChecks.%s: unsupported operator %q, must be one of [<|<=|>|>=|==|!=]",
+					check.Name, check.Prometheus.Operator))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (c *Config) validatePrerequisites() []error {
+	var errs []error
+
+	for _, name := range c.Prerequisites.Enabled {
+		switch name {
+		case "kernel", "bpf", "geneve", "images", "kubernetesVersion":
+		default:
+			errs = append(errs, fmt.Errorf("prerequisites.enabled: unknown check %q, must be one of [kernel|bpf|geneve|images|kubernetesVersion]", name))
+		}
+	}
+
+	if *c.Prerequisites.GenevePeers < 0 {
+		errs = append(errs, fmt.Errorf("prerequisites.genevePeers: must not be negative"))
+	}
+
+	for _, secret := range c.Prerequisites.ImagePullSecrets {
+		parts := strings.Split(secret, "/")
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			errs = append(errs, fmt.Errorf("prerequisites.imagePullSecrets: invalid secret %q, must be <namespace>/<name>", secret))
+		}
+	}
+
+	for _, v := range []struct {
+		field, version string
+	}{
+		{"minKernelVersion", c.Prerequisites.MinKernelVersion},
+		{"minKubernetesVersion", c.Prerequisites.MinKubernetesVersion},
+		{"maxKubernetesVersion", c.Prerequisites.MaxKubernetesVersion},
+	} {
+		if _, err := version.ParseGeneric(v.version); err != nil {
+			errs = append(errs, fmt.Errorf("prerequisites.%s: %s", v.field, err))
+		}
+	}
+
+	return errs
+}
+
+// validateBundles checks every value the manifest bundles are rendered with.
+func (c *Config) validateBundles() []error {
+	var errs []error
+
+	for _, image := range []struct {
+		field string
+		image *Image
+	}{
+		{"cilium", c.Bundles.Images.Cilium},
+		{"ciliumOperator", c.Bundles.Images.CiliumOperator},
+		{"multus", c.Bundles.Images.Multus},
+		{"probe", c.Bundles.Images.Probe},
+	} {
+		// A colon may only be the port of the registry.
+		repository := image.image.Repository
+		if name := repository[strings.LastIndex(repository, "/")+1:]; strings.ContainsAny(name, ":@") {
+			errs = append(errs, fmt.Errorf("bundles.images.%s.repository: invalid repository %q, must not contain a tag or digest",
+				image.field, image.image.Repository))
+		}
+	}
+
+	switch c.Bundles.TunnelMode {
+	// VXLAN is not supported, since the VXLAN interface of flannel is already
+	// present on every node.
+	case "geneve", "disabled":
+	default:
+		errs = append(errs, fmt.Errorf("bundles.tunnelMode: unsupported tunnel mode %q, must be one of [geneve|disabled]",
+			c.Bundles.TunnelMode))
+	}
+
+	if len(c.Bundles.ClusterCIDR) > 0 {
+		if _, _, err := net.ParseCIDR(c.Bundles.ClusterCIDR); err != nil {
+			errs = append(errs, fmt.Errorf("bundles.clusterCIDR: %s", err))
+		}
+	}
+
+	for _, dir := range []struct {
+		field, dir string
+	}{
+		{"cniConfDir", c.Bundles.CNIConfDir},
+		{"cniBinDir", c.Bundles.CNIBinDir},
+	} {
+		if !path.IsAbs(dir.dir) {
+			errs = append(errs, fmt.Errorf("bundles.%s: %q must be an absolute path", dir.field, dir.dir))
+		}
+	}
+
+	for _, file := range []struct {
+		field, name string
+	}{
+		{"bundles.conflists.cilium", c.Bundles.Conflists.Cilium},
+		{"bundles.conflists.multus", c.Bundles.Conflists.Multus},
+		{"bundles.conflists.flannel", c.Bundles.Conflists.Flannel},
+		{"cni.source.configFile", c.CNI.Source.ConfigFile},
+		{"cni.target.configFile", c.CNI.Target.ConfigFile},
+	} {
+		if len(file.name) == 0 || strings.Contains(file.name, "/") {
+			errs = append(errs, fmt.Errorf("%s: invalid file name %q", file.field, file.name))
+		}
+	}
+
+	return errs
+}
+
+// validatePatches checks every patch has a supported type and a patch
+// document, and every key matches an object of the patched bundles. Errors
+// applying patches are reported by validatePaths.
+func (c *Config) validatePatches() []error {
+	keys := make(map[string]bool)
+	for _, path := range []string{c.Paths.Cilium, c.Paths.Multus, c.Paths.KnetStress} {
+		// Errors reading the bundle are reported by validatePaths.
+		objs, err := manifest.Read(path, c.Values())
+		if err != nil {
+			continue
+		}
+
+		for _, obj := range objs {
+			keys[PatchKey(obj)] = true
+		}
+	}
+
+	var names []string
+	for key := range c.Patches {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, key := range names {
+		if !keys[key] {
+			errs = append(errs, fmt.Errorf("patches.%s: does not match any object of the cilium, multus or knet-stress bundles", key))
+		}
+
+		for i, patch := range c.Patches[key] {
+			if patch == nil || len(strings.TrimSpace(patch.Patch)) == 0 {
+				errs = append(errs, fmt.Errorf("patches.%s[%d].patch: required", key, i))
+				continue
+			}
+
+			switch patch.Type {
+			case manifest.PatchTypeStrategic, manifest.PatchTypeJSON6902:
+			default:
+				errs = append(errs, fmt.Errorf("patches.%s[%d].type: unsupported patch type %q, must be one of [%s|%s]",
+					key, i, patch.Type, manifest.PatchTypeStrategic, manifest.PatchTypeJSON6902))
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTimeouts(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		mutate func(c *Config)
+		err    string
+	}{
+		{"example config", func(c *Config) {}, ""},
+		{"drain within node", func(c *Config) {
+			c.Drain.Timeout = time.Minute * 10
+			c.Timeouts.Node = time.Minute * 30
+		}, ""},
+		{"drain unbounded within node", func(c *Config) {
+			c.Drain.Timeout = 0
+			c.Timeouts.Node = time.Minute * 30
+		}, ""},
+		{"drain exceeds node", func(c *Config) {
+			c.Drain.Timeout = time.Hour
+			c.Timeouts.Node = time.Minute * 30
+		}, "drain.timeout: 1h0m0s must not exceed timeouts.node"},
+		{"drain with node unbounded", func(c *Config) {
+			c.Drain.Timeout = time.Hour
+			c.Timeouts.Node = 0
+		}, ""},
+		{"readiness alias matches", func(c *Config) {
+			c.Timeouts.Readiness = c.Readiness.Timeout
+		}, ""},
+		{"readiness alias conflicts", func(c *Config) {
+			c.Timeouts.Readiness = time.Minute * 5
+			c.Readiness.Timeout = time.Minute * 10
+		}, "timeouts.readiness: 5m0s conflicts with readiness.timeout (10m0s)"},
+		{"knet-stress exceeds connectivity", func(c *Config) {
+			c.KnetStress.Timeout = time.Minute * 15
+			c.Timeouts.Connectivity = time.Minute * 10
+		}, "knetStress.timeout: 15m0s must not exceed timeouts.connectivity"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := Read("../../config.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.mutate(c)
+
+			err = c.Validate()
+			switch {
+			case len(test.err) == 0 && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestValidateHealthChecks(t *testing.T) {
+	exec := &ExecHealthCheck{Namespace: "default", Selector: "app=app", Command: []string{"true"}}
+
+	for _, test := range []struct {
+		name   string
+		checks []*HealthCheck
+		err    string
+	}{
+		{"valid", []*HealthCheck{{Name: "app", Retries: 3, Exec: exec}}, ""},
+		{"empty entry", []*HealthCheck{{Name: "app", Exec: exec}, nil}, "healthChecks[1]: must not be empty"},
+		{"negative retries", []*HealthCheck{{Name: "app", Retries: -1, Exec: exec}}, "healthChecks.app.retries: must not be negative"},
+		{"negative timeout", []*HealthCheck{{Name: "app", Timeout: -time.Second, Exec: exec}}, "healthChecks.app.timeout: must not be negative"},
+		{"no check", []*HealthCheck{{Name: "app"}}, "healthChecks.app: must have exactly one of http, exec or prometheus"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := Read("../../config.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Defaults must not panic on empty entries.
+			c.HealthChecks = test.checks
+			c.setDefaults()
+
+			err = c.Validate()
+			switch {
+			case len(test.err) == 0 && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestValidateChecks(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		enabled []string
+		url     string
+		err     string
+	}{
+		{"none", nil, "", ""},
+		{"built-in", []string{"clusterIP", "dns", "nodePort", "networkPolicy"}, "", ""},
+		{"egress with url", []string{"egress"}, "https://example.com", ""},
+		{"egress without url", []string{"egress"}, "", "checks.egress.url: required when the egress check is enabled"},
+		{"unknown", []string{"dns", "dsn"}, "", `checks.enabled: unknown check "dsn"`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := Read("../../config.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c.Checks.Enabled = test.enabled
+			c.Checks.Egress.URL = test.url
+
+			err = c.Validate()
+			switch {
+			case len(test.err) == 0 && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"strings"
+)
+
+// Values are the values every manifest bundle is rendered with.
+type Values struct {
+	Images      ImageValues
+	TunnelMode  string
+	ClusterCIDR string
+	CNIConfDir  string
+	CNIBinDir   string
+	Conflists   ConflistValues
+	Labels      *Labels
+}
+
+// ImageValues are the full references of each bundle image, with the registry
+// override applied.
+type ImageValues struct {
+	Cilium         string
+	CiliumOperator string
+	Multus         string
+	Probe          string
+}
+
+// ConflistValues are the file names of every CNI config on each node.
+type ConflistValues struct {
+	// Source is the config of the source CNI.
+	Source string
+	// Cilium is the config of Cilium running alongside the source CNI.
+	Cilium string
+	// CiliumMigrated is the config of Cilium on migrated nodes.
+	CiliumMigrated string
+	Multus         string
+	Flannel        string
+}
+
+// Values returns the values to render the manifest bundles with. Defaults
+// must already be set.
+func (c *Config) Values() *Values {
+	b := c.Bundles
+
+	return &Values{
+		Images: ImageValues{
+			Cilium:         b.Images.Cilium.Reference(b.Registry),
+			CiliumOperator: b.Images.CiliumOperator.Reference(b.Registry),
+			Multus:         b.Images.Multus.Reference(b.Registry),
+			Probe:          b.Images.Probe.Reference(b.Registry),
+		},
+		TunnelMode:  b.TunnelMode,
+		ClusterCIDR: b.ClusterCIDR,
+		CNIConfDir:  strings.TrimSuffix(b.CNIConfDir, "/"),
+		CNIBinDir:   strings.TrimSuffix(b.CNIBinDir, "/"),
+		Conflists: ConflistValues{
+			Source:         c.CNI.Source.ConfigFile,
+			Cilium:         b.Conflists.Cilium,
+			CiliumMigrated: c.CNI.Target.ConfigFile,
+			Multus:         b.Conflists.Multus,
+			Flannel:        b.Conflists.Flannel,
+		},
+		Labels: c.Labels,
+	}
+}
+
+// Reference returns the image reference of the repository and tag. If
+// registry is set, it replaces the registry of the repository, or is
+// prepended if the repository has none.
+func (i *Image) Reference(registry string) string {
+	repository := i.Repository
+
+	if len(registry) > 0 {
+		parts := strings.SplitN(repository, "/", 2)
+		if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+			repository = parts[1]
+		}
+		repository = strings.TrimSuffix(registry, "/") + "/" + repository
+	}
+
+	return repository + ":" + i.Tag
+}
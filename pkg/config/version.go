@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// GroupName is the API group of the config.
+	GroupName = "cni-migration.jetstack.io"
+
+	// APIVersion is the current version of the config. Configs of older
+	// versions are converted to this version when read.
+	APIVersion = GroupName + "/v1alpha1"
+
+	// Kind is the kind of the config.
+	Kind = "MigrationConfig"
+)
+
+// TypeMeta holds the version and kind of the config.
+type TypeMeta struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+}
+
+// conversion converts a config from one version to the next. Conversions
+// operate on the raw YAML, since older configs may not unmarshal strictly
+// into the current version.
+type conversion struct {
+	to      string
+	convert func(yaml.MapSlice) (yaml.MapSlice, error)
+}
+
+// conversions are keyed by the version they convert from. Configs without an
+// apiVersion are the unversioned configs which predate v1alpha1.
+var conversions = map[string]conversion{
+	"": {to: APIVersion, convert: convertUnversioned},
+}
+
+// Convert will convert the YAML config to the current version, returning the
+// converted config, along with the version it was converted from. Comments
+// are not preserved.
+func Convert(data []byte) ([]byte, string, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, "", err
+	}
+
+	if kind, ok := lookup(doc, "kind"); ok && kind != Kind {
+		return nil, "", fmt.Errorf("unsupported kind %q, must be %q", kind, Kind)
+	}
+
+	from, _ := lookup(doc, "apiVersion")
+	if _, ok := lookup(doc, "kind"); !ok && len(from) > 0 {
+		return nil, "", fmt.Errorf("kind is required, must be %q", Kind)
+	}
+
+	for version := from; version != APIVersion; {
+		c, ok := conversions[version]
+		if !ok {
+			return nil, "", fmt.Errorf("unsupported apiVersion %q, must be %q", version, APIVersion)
+		}
+
+		var err error
+		doc, err = c.convert(doc)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to convert config from %q to %q: %s", version, c.to, err)
+		}
+
+		version = c.to
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return out, from, nil
+}
+
+// unversionedCanalConfigFile is the CNI config file of canal which the multus
+// bundle of unversioned configs was always chained to.
+const unversionedCanalConfigFile = "10-calico.conflist"
+
+// convertUnversioned converts an unversioned config to v1alpha1. The fields
+// of both are the same, so the apiVersion and kind are set, the config file
+// of a canal source is pinned to the file unversioned configs used, and no
+// connectivity checks are enabled, since unversioned configs predate them.
+func convertUnversioned(doc yaml.MapSlice) (yaml.MapSlice, error) {
+	var out yaml.MapSlice
+	out = append(out,
+		yaml.MapItem{Key: "apiVersion", Value: APIVersion},
+		yaml.MapItem{Key: "kind", Value: Kind},
+	)
+
+	for _, item := range doc {
+		if item.Key == "apiVersion" || item.Key == "kind" {
+			continue
+		}
+		out = append(out, item)
+	}
+
+	cni, _ := lookupMap(out, "cni")
+	source, _ := lookupMap(cni, "source")
+	if name, _ := lookup(source, "name"); len(name) == 0 || name == CNICanal {
+		if _, ok := lookup(source, "configFile"); !ok {
+			source = set(source, "configFile", unversionedCanalConfigFile)
+			cni = set(cni, "source", source)
+			out = set(out, "cni", cni)
+		}
+	}
+
+	checks, _ := lookupMap(out, "checks")
+	if !has(checks, "enabled") {
+		checks = set(checks, "enabled", []string{})
+		out = set(out, "checks", checks)
+	}
+
+	return out, nil
+}
+
+// has returns true if the top level key is present in the document.
+func has(doc yaml.MapSlice, key string) bool {
+	for _, item := range doc {
+		if k, ok := item.Key.(string); ok && k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookup returns the string value of the top level key of the document.
+func lookup(doc yaml.MapSlice, key string) (string, bool) {
+	for _, item := range doc {
+		if k, ok := item.Key.(string); ok && k == key {
+			s, ok := item.Value.(string)
+			return s, ok
+		}
+	}
+
+	return "", false
+}
+
+// lookupMap returns the mapping value of the top level key of the document.
+func lookupMap(doc yaml.MapSlice, key string) (yaml.MapSlice, bool) {
+	for _, item := range doc {
+		if k, ok := item.Key.(string); ok && k == key {
+			m, ok := item.Value.(yaml.MapSlice)
+			return m, ok
+		}
+	}
+
+	return nil, false
+}
+
+// set sets the value of the top level key of the document, appending the key
+// if it is not present.
+func set(doc yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range doc {
+		if k, ok := item.Key.(string); ok && k == key {
+			doc[i].Value = value
+			return doc
+		}
+	}
+
+	return append(doc, yaml.MapItem{Key: key, Value: value})
+}
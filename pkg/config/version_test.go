@@ -0,0 +1,116 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestConvert(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		config   string
+		wantFrom string
+		want     map[string]interface{}
+		err      string
+	}{
+		{
+			name:     "current version is unchanged",
+			config:   "apiVersion: " + APIVersion + "\nkind: " + Kind + "\nbatch:\n  maxUnavailable: 2\n",
+			wantFrom: APIVersion,
+			want: map[string]interface{}{
+				"apiVersion": APIVersion,
+				"kind":       Kind,
+				"batch":      map[interface{}]interface{}{"maxUnavailable": 2},
+			},
+		},
+		{
+			name:     "unversioned is converted",
+			config:   "batch:\n  maxUnavailable: 2\ncni:\n  source:\n    name: flannel\n",
+			wantFrom: "",
+			want: map[string]interface{}{
+				"apiVersion": APIVersion,
+				"kind":       Kind,
+				"batch":      map[interface{}]interface{}{"maxUnavailable": 2},
+				"checks":     map[interface{}]interface{}{"enabled": []interface{}{}},
+				"cni": map[interface{}]interface{}{
+					"source": map[interface{}]interface{}{"name": "flannel"},
+				},
+			},
+		},
+		{
+			name:     "unversioned with kind is converted",
+			config:   "kind: " + Kind + "\n",
+			wantFrom: "",
+			want: map[string]interface{}{
+				"apiVersion": APIVersion,
+				"kind":       Kind,
+				"checks":     map[interface{}]interface{}{"enabled": []interface{}{}},
+				"cni": map[interface{}]interface{}{
+					"source": map[interface{}]interface{}{"configFile": unversionedCanalConfigFile},
+				},
+			},
+		},
+		{
+			name:     "unversioned enabled checks are kept",
+			config:   "checks:\n  enabled: [dns]\ncni:\n  source:\n    name: calico\n",
+			wantFrom: "",
+			want: map[string]interface{}{
+				"apiVersion": APIVersion,
+				"kind":       Kind,
+				"checks":     map[interface{}]interface{}{"enabled": []interface{}{"dns"}},
+				"cni": map[interface{}]interface{}{
+					"source": map[interface{}]interface{}{"name": "calico"},
+				},
+			},
+		},
+		{
+			name:   "unsupported kind",
+			config: "apiVersion: " + APIVersion + "\nkind: Pod\n",
+			err:    `unsupported kind "Pod"`,
+		},
+		{
+			name:   "versioned without kind",
+			config: "apiVersion: " + APIVersion + "\n",
+			err:    "kind is required",
+		},
+		{
+			name:   "unsupported version",
+			config: "apiVersion: " + GroupName + "/v1\nkind: " + Kind + "\n",
+			err:    `unsupported apiVersion "` + GroupName + `/v1"`,
+		},
+		{
+			name:   "invalid yaml",
+			config: "batch: [",
+			err:    "yaml",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data, from, err := Convert([]byte(test.config))
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if from != test.wantFrom {
+				t.Errorf("got converted from %q, want %q", from, test.wantFrom)
+			}
+
+			got := make(map[string]interface{})
+			if err := yaml.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
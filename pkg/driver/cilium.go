@@ -0,0 +1,96 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+var _ CNIDriver = &Cilium{}
+
+// Cilium is the driver for Cilium, deployed from the Cilium manifest bundle as
+// two DaemonSets. The first runs alongside the source CNI on all nodes before
+// migration. The second runs only on nodes which are being, or have been,
+// migrated, and writes its CNI config with the highest priority.
+type Cilium struct {
+	*daemonSetDriver
+
+	config            *config.Config
+	migratedDaemonSet string
+}
+
+func NewCilium(ctx context.Context, log *logrus.Entry, cfg *config.Config, d *config.Driver) *Cilium {
+	return &Cilium{
+		daemonSetDriver:   newDaemonSetDriver(ctx, log, cfg, d),
+		config:            cfg,
+		migratedDaemonSet: d.MigratedDaemonSet,
+	}
+}
+
+// Install will apply the Cilium manifest bundle and wait for it to become
+// ready.
+func (c *Cilium) Install(dryrun bool) error {
+	return c.factory.CreateResource(dryrun, c.config.Paths.Cilium, c.namespace)
+}
+
+func (c *Cilium) Installed() (bool, error) {
+	return c.factory.Has(&config.Resources{
+		DaemonSets: map[string][]string{
+			c.namespace: {c.daemonSet, c.migratedDaemonSet},
+		},
+	})
+}
+
+func (c *Cilium) Ready() error {
+	if err := c.factory.WaitDaemonSetReady(c.namespace, c.daemonSet); err != nil {
+		return err
+	}
+
+	return c.factory.WaitDaemonSetReady(c.namespace, c.migratedDaemonSet)
+}
+
+// CleanUp will remove the migrated node selector from the migrated
+// DaemonSet, so that it runs on all nodes, and delete the first DaemonSet.
+func (c *Cilium) CleanUp(dryrun bool) error {
+	c.log.Infof("removing node selector from %s", c.migratedDaemonSet)
+	if !dryrun {
+		ds, err := c.client.AppsV1().DaemonSets(c.namespace).Get(c.ctx, c.migratedDaemonSet, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		delete(ds.Spec.Template.Spec.NodeSelector, c.config.Labels.Cilium)
+
+		_, err = c.client.AppsV1().DaemonSets(c.namespace).Update(c.ctx, ds, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.daemonSetDriver.CleanUp(dryrun)
+}
+
+func (c *Cilium) CleanedUp() (bool, error) {
+	_, err := c.client.AppsV1().DaemonSets(c.namespace).Get(c.ctx, c.daemonSet, metav1.GetOptions{})
+	if err == nil {
+		return false, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	ds, err := c.client.AppsV1().DaemonSets(c.namespace).Get(c.ctx, c.migratedDaemonSet, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := ds.Spec.Template.Spec.NodeSelector[c.config.Labels.Cilium]; ok {
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,177 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/util"
+)
+
+var _ CNIDriver = &daemonSetDriver{}
+
+// daemonSetDriver is a CNI which is deployed as a single DaemonSet, and is
+// expected to already be installed in the cluster. Every source CNI, Canal,
+// Flannel and Calico, is driven by it, differing only in the configured
+// namespace and DaemonSet. It is also the base of the Cilium driver.
+type daemonSetDriver struct {
+	ctx context.Context
+	log *logrus.Entry
+
+	client  kubernetes.Interface
+	factory *util.Factory
+
+	name      string
+	namespace string
+	daemonSet string
+}
+
+func newDaemonSetDriver(ctx context.Context, log *logrus.Entry, cfg *config.Config, d *config.Driver) *daemonSetDriver {
+	log = log.WithField("cni", d.Name)
+	return &daemonSetDriver{
+		ctx:       ctx,
+		log:       log,
+		client:    cfg.Client,
+		factory:   util.New(ctx, log, cfg),
+		name:      d.Name,
+		namespace: d.Namespace,
+		daemonSet: d.DaemonSet,
+	}
+}
+
+func (d *daemonSetDriver) Name() string {
+	return d.name
+}
+
+func (d *daemonSetDriver) Install(dryrun bool) error {
+	installed, err := d.Installed()
+	if err != nil {
+		return err
+	}
+
+	if !installed {
+		return fmt.Errorf("%s DaemonSet %s/%s not found, %s must already be installed",
+			d.name, d.namespace, d.daemonSet, d.name)
+	}
+
+	if dryrun {
+		return nil
+	}
+
+	return d.Ready()
+}
+
+func (d *daemonSetDriver) Installed() (bool, error) {
+	_, err := d.client.AppsV1().DaemonSets(d.namespace).Get(d.ctx, d.daemonSet, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (d *daemonSetDriver) PatchSelector(key, value string) error {
+	ds, err := d.client.AppsV1().DaemonSets(d.namespace).Get(d.ctx, d.daemonSet, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Save the original node selector so that it can be restored on rollback
+	if _, ok := ds.Annotations[OriginalNodeSelectorAnnotation]; !ok {
+		original, err := json.Marshal(ds.Spec.Template.Spec.NodeSelector)
+		if err != nil {
+			return err
+		}
+
+		if ds.Annotations == nil {
+			ds.Annotations = make(map[string]string)
+		}
+		ds.Annotations[OriginalNodeSelectorAnnotation] = string(original)
+	}
+
+	if ds.Spec.Template.Spec.NodeSelector == nil {
+		ds.Spec.Template.Spec.NodeSelector = make(map[string]string)
+	}
+	ds.Spec.Template.Spec.NodeSelector[key] = value
+
+	_, err = d.client.AppsV1().DaemonSets(d.namespace).Update(d.ctx, ds, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *daemonSetDriver) SelectorPatched(key, value string) (bool, error) {
+	ds, err := d.client.AppsV1().DaemonSets(d.namespace).Get(d.ctx, d.daemonSet, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	if v, ok := ds.Spec.Template.Spec.NodeSelector[key]; !ok || v != value {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (d *daemonSetDriver) RestoreSelector(key string) error {
+	ds, err := d.client.AppsV1().DaemonSets(d.namespace).Get(d.ctx, d.daemonSet, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	original, ok := ds.Annotations[OriginalNodeSelectorAnnotation]
+	if ok {
+		var nodeSelector map[string]string
+		if err := json.Unmarshal([]byte(original), &nodeSelector); err != nil {
+			return fmt.Errorf("failed to decode original %s node selector %q: %s", d.name, original, err)
+		}
+
+		ds.Spec.Template.Spec.NodeSelector = nodeSelector
+		delete(ds.Annotations, OriginalNodeSelectorAnnotation)
+	} else {
+		d.log.Warnf("%s DaemonSet has no %s annotation, removing %s from node selector",
+			d.name, OriginalNodeSelectorAnnotation, key)
+		delete(ds.Spec.Template.Spec.NodeSelector, key)
+	}
+
+	_, err = d.client.AppsV1().DaemonSets(d.namespace).Update(d.ctx, ds, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *daemonSetDriver) Ready() error {
+	return d.factory.WaitDaemonSetReady(d.namespace, d.daemonSet)
+}
+
+func (d *daemonSetDriver) CleanUp(dryrun bool) error {
+	d.log.Infof("deleting %s DaemonSet %s/%s", d.name, d.namespace, d.daemonSet)
+	if dryrun {
+		return nil
+	}
+
+	err := d.client.AppsV1().DaemonSets(d.namespace).Delete(d.ctx, d.daemonSet, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (d *daemonSetDriver) CleanedUp() (bool, error) {
+	installed, err := d.Installed()
+	return !installed, err
+}
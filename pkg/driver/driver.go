@@ -0,0 +1,61 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+const (
+	// OriginalNodeSelectorAnnotation holds the node selector of a CNI
+	// DaemonSet before it was patched.
+	OriginalNodeSelectorAnnotation = "cni-migration.jetstack.io/original-node-selector"
+)
+
+// CNIDriver manages a CNI which is either the source or target of the
+// migration.
+type CNIDriver interface {
+	// Name returns the name of the CNI.
+	Name() string
+
+	// Install will ensure the CNI is installed in the cluster and ready.
+	Install(dryrun bool) error
+
+	// Installed returns whether the CNI is installed in the cluster.
+	Installed() (bool, error)
+
+	// PatchSelector will add the node selector key=value to the CNI
+	// DaemonSet, saving the original node selector.
+	PatchSelector(key, value string) error
+
+	// SelectorPatched returns whether the CNI DaemonSet has the node selector
+	// key=value.
+	SelectorPatched(key, value string) (bool, error)
+
+	// RestoreSelector will restore the original node selector of the CNI
+	// DaemonSet, before it was patched.
+	RestoreSelector(key string) error
+
+	// Ready will wait for all DaemonSets of the CNI to become ready.
+	Ready() error
+
+	// CleanUp will remove all resources of the CNI which are no longer
+	// required once the migration is complete.
+	CleanUp(dryrun bool) error
+
+	// CleanedUp returns whether CleanUp has been completed.
+	CleanedUp() (bool, error)
+}
+
+// Source returns the driver of the CNI being migrated from. Every supported
+// source CNI is a single DaemonSet.
+func Source(ctx context.Context, log *logrus.Entry, cfg *config.Config) CNIDriver {
+	return newDaemonSetDriver(ctx, log, cfg, cfg.CNI.Source)
+}
+
+// Target returns the driver of the CNI being migrated to.
+func Target(ctx context.Context, log *logrus.Entry, cfg *config.Config) CNIDriver {
+	return NewCilium(ctx, log, cfg, cfg.CNI.Target)
+}
@@ -0,0 +1,322 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/jetstack/cni-migration/pkg/version"
+)
+
+const (
+	// Namespace and Name of the ConfigMap holding the migration ledger.
+	Namespace = "kube-system"
+	Name      = "cni-migration"
+
+	dataKey = "ledger.json"
+)
+
+var (
+	// updateLock serialises every update of the ledger in this process, so
+	// that nodes processed concurrently in a batch never conflict.
+	updateLock sync.Mutex
+
+	// updateBackoff retries updates conflicting with other processes for
+	// around 25 seconds.
+	updateBackoff = wait.Backoff{
+		Steps:    8,
+		Duration: 100 * time.Millisecond,
+		Factor:   2.0,
+		Jitter:   0.1,
+	}
+)
+
+// Record is a single entry of the ledger, recording an execution of either
+// a step, or a step on a single node.
+type Record struct {
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Operator string     `json:"operator"`
+	Version  string     `json:"version"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// State is the full contents of the ledger.
+type State struct {
+	// Steps is a record of each step, keyed by step name.
+	Steps map[string]*Record `json:"steps,omitempty"`
+
+	// Nodes is a record of each step per node, keyed by node then step name.
+	Nodes map[string]map[string]*Record `json:"nodes,omitempty"`
+}
+
+// Ledger persists the progress of the migration in a ConfigMap, so that
+// interrupted runs can be audited. The ledger is not used to resume a run;
+// nodes are resumed from their checkpoint, see util.CheckpointAnnotation.
+type Ledger struct {
+	ctx context.Context
+	log *logrus.Entry
+
+	client   kubernetes.Interface
+	operator string
+}
+
+func New(ctx context.Context, log *logrus.Entry, client kubernetes.Interface) *Ledger {
+	return &Ledger{
+		ctx:      ctx,
+		log:      log,
+		client:   client,
+		operator: operator(),
+	}
+}
+
+// RecordStep will record the execution of fn as the given step. Nothing is
+// recorded during a dry run.
+func (l *Ledger) RecordStep(dryrun bool, step string, fn func() error) error {
+	if dryrun {
+		return fn()
+	}
+
+	return l.record(func(s *State) map[string]*Record {
+		return s.Steps
+	}, step, fn)
+}
+
+// RecordNode will record the execution of fn as the given step on a node.
+// Nothing is recorded during a dry run.
+func (l *Ledger) RecordNode(dryrun bool, step, nodeName string, fn func() error) error {
+	if dryrun {
+		return fn()
+	}
+
+	return l.record(func(s *State) map[string]*Record {
+		if s.Nodes[nodeName] == nil {
+			s.Nodes[nodeName] = make(map[string]*Record)
+		}
+		return s.Nodes[nodeName]
+	}, step, fn)
+}
+
+func (l *Ledger) record(records func(*State) map[string]*Record, step string, fn func() error) error {
+	err := l.update(func(s *State) {
+		records(s)[step] = &Record{
+			Started:  time.Now().UTC(),
+			Operator: l.operator,
+			Version:  version.Version,
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update ledger: %s", err)
+	}
+
+	fnErr := fn()
+
+	err = l.update(func(s *State) {
+		r, ok := records(s)[step]
+		if !ok {
+			r = &Record{Operator: l.operator, Version: version.Version}
+			records(s)[step] = r
+		}
+
+		if fnErr != nil {
+			r.Error = fnErr.Error()
+			return
+		}
+
+		now := time.Now().UTC()
+		r.Finished = &now
+		r.Error = ""
+	})
+	if err != nil {
+		// Don't mask the error of the step itself
+		if fnErr != nil {
+			l.log.Errorf("failed to update ledger: %s", err)
+			return fnErr
+		}
+
+		return fmt.Errorf("failed to update ledger: %s", err)
+	}
+
+	return fnErr
+}
+
+// Get returns the current state of the ledger. An empty state is returned if
+// no ledger exists.
+func (l *Ledger) Get() (*State, error) {
+	cm, err := l.client.CoreV1().ConfigMaps(Namespace).Get(l.ctx, Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return newState(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(cm)
+}
+
+// LogInterrupted will log a warning for every step or node which was started
+// but did not finish successfully, so that operators know which runs were
+// interrupted.
+func (l *Ledger) LogInterrupted() error {
+	state, err := l.Get()
+	if err != nil {
+		return fmt.Errorf("failed to read ledger: %s", err)
+	}
+
+	for _, step := range sortedKeys(state.Steps) {
+		if r := state.Steps[step]; r.Finished == nil {
+			l.log.Warnf("step %s was started at %s by %s (%s) but did not finish: %s",
+				step, r.Started.Format(time.RFC3339), r.Operator, r.Version, errorOrInterrupted(r))
+		}
+	}
+
+	var nodeNames []string
+	for nodeName := range state.Nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	for _, nodeName := range nodeNames {
+		for _, step := range sortedKeys(state.Nodes[nodeName]) {
+			if r := state.Nodes[nodeName][step]; r.Finished == nil {
+				l.log.Warnf("step %s on node %s was started at %s by %s (%s) but did not finish: %s",
+					step, nodeName, r.Started.Format(time.RFC3339), r.Operator, r.Version, errorOrInterrupted(r))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *Ledger) update(mutate func(*State)) error {
+	updateLock.Lock()
+	defer updateLock.Unlock()
+
+	return retry.RetryOnConflict(updateBackoff, func() error {
+		cm, err := l.client.CoreV1().ConfigMaps(Namespace).Get(l.ctx, Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: Namespace,
+					Name:      Name,
+				},
+			}
+
+			state := newState()
+			mutate(state)
+			if err := encode(cm, state); err != nil {
+				return err
+			}
+
+			_, err = l.client.CoreV1().ConfigMaps(Namespace).Create(l.ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Force a retry
+				return apierrors.NewConflict(corev1.Resource("configmaps"), Name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		state, err := decode(cm)
+		if err != nil {
+			return err
+		}
+
+		mutate(state)
+		if err := encode(cm, state); err != nil {
+			return err
+		}
+
+		_, err = l.client.CoreV1().ConfigMaps(Namespace).Update(l.ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func newState() *State {
+	return &State{
+		Steps: make(map[string]*Record),
+		Nodes: make(map[string]map[string]*Record),
+	}
+}
+
+func decode(cm *corev1.ConfigMap) (*State, error) {
+	state := newState()
+
+	data, ok := cm.Data[dataKey]
+	if !ok || len(data) == 0 {
+		return state, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), state); err != nil {
+		return nil, fmt.Errorf("failed to decode ledger %s/%s: %s", Namespace, Name, err)
+	}
+
+	if state.Steps == nil {
+		state.Steps = make(map[string]*Record)
+	}
+	if state.Nodes == nil {
+		state.Nodes = make(map[string]map[string]*Record)
+	}
+
+	return state, nil
+}
+
+func encode(cm *corev1.ConfigMap, state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[dataKey] = string(data)
+
+	return nil
+}
+
+// operator returns an identifier of the user running the migration.
+func operator() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		name += "@" + hostname
+	}
+
+	return name
+}
+
+func errorOrInterrupted(r *Record) string {
+	if len(r.Error) > 0 {
+		return r.Error
+	}
+
+	return "interrupted"
+}
+
+func sortedKeys(records map[string]*Record) []string {
+	var keys []string
+	for k := range records {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,57 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestRecordNodeConcurrently ensures no records are lost when the nodes of a
+// batch are recorded concurrently.
+func TestRecordNodeConcurrently(t *testing.T) {
+	const nodes = 100
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	l := New(context.Background(), logrus.NewEntry(logger), fake.NewSimpleClientset())
+
+	var wg sync.WaitGroup
+	errs := make([]error, nodes)
+	for i := 0; i < nodes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = l.RecordNode(false, "2-roll", fmt.Sprintf("node-%d", i), func() error {
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("node-%d: %s", i, err)
+		}
+	}
+
+	state, err := l.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(state.Nodes) != nodes {
+		t.Fatalf("got %d nodes recorded, want %d", len(state.Nodes), nodes)
+	}
+
+	for name, records := range state.Nodes {
+		if r := records["2-roll"]; r == nil || r.Finished == nil {
+			t.Errorf("%s: expected finished record, got %+v", name, r)
+		}
+	}
+}
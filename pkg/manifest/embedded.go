@@ -0,0 +1,28 @@
+package manifest
+
+//go:generate go run ../../hack/gen-bundles -o zz_generated.bundles.go ../../resources/cilium.yaml ../../resources/multus.yaml ../../resources/knet-stress.yaml ../../resources/network-policy.yaml
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EmbeddedScheme prefixes the paths of the manifest bundles embedded in the
+// binary, e.g. embedded://cilium.yaml.
+const EmbeddedScheme = "embedded://"
+
+// Embedded returns the path of the embedded manifest bundle of the given file
+// name.
+func Embedded(name string) string {
+	return EmbeddedScheme + name
+}
+
+// readEmbedded returns the contents of the embedded manifest bundle at path.
+func readEmbedded(path string) ([]byte, error) {
+	data, ok := embedded[strings.TrimPrefix(path, EmbeddedScheme)]
+	if !ok {
+		return nil, fmt.Errorf("no embedded manifest %q", path)
+	}
+
+	return []byte(data), nil
+}
@@ -0,0 +1,168 @@
+// Package manifest renders and decodes the Kubernetes objects of manifest
+// bundles.
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Read will render the manifest bundle template at the given path with
+// values, and parse all Kubernetes objects from the rendered multi-document
+// YAML or JSON.
+func Read(filePath string, values interface{}) ([]*unstructured.Unstructured, error) {
+	data, err := Render(filePath, values)
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %q: %s", filePath, err)
+	}
+
+	return objs, nil
+}
+
+// Render will execute the manifest bundle at the given path as a Go
+// template with values. Referencing a value which does not exist is an
+// error.
+func Render(filePath string, values interface{}) ([]byte, error) {
+	data, err := Load(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %q: %s", filePath, err)
+	}
+
+	tmpl, err := template.New(filepath.Base(filePath)).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest template %q: %s", filePath, err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, values); err != nil {
+		return nil, fmt.Errorf("failed to render manifest %q: %s", filePath, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Load will return the unrendered manifest bundle at the given path. The path
+// is either an embedded bundle prefixed with EmbeddedScheme, a URL fetched
+// over HTTP(S), a directory of which every .yaml, .yml and .json file is
+// concatenated in file name order, or a file.
+func Load(path string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(path, EmbeddedScheme):
+		return readEmbedded(path)
+	case strings.HasPrefix(path, "http://"), strings.HasPrefix(path, "https://"):
+		return readURL(path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return readDir(path)
+	}
+
+	return ioutil.ReadFile(path)
+}
+
+// readURL will GET the manifest bundle from the URL.
+func readURL(url string) ([]byte, error) {
+	client := &http.Client{Timeout: time.Second * 30}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// readDir will concatenate every manifest file in the directory as a
+// multi-document bundle, in file name order. Sub directories are ignored.
+func readDir(dir string) ([]byte, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+
+		switch filepath.Ext(info.Name()) {
+		case ".yaml", ".yml", ".json":
+			names = append(names, info.Name())
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no .yaml, .yml or .json files in directory")
+	}
+
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		buf.WriteString("\n---\n")
+		buf.Write(data)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode will decode all Kubernetes objects from the multi-document
+// YAML or JSON stream. Empty documents are skipped.
+func Decode(r io.Reader) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+
+	var objs []*unstructured.Unstructured
+	for {
+		obj := new(unstructured.Unstructured)
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("object %d is missing apiVersion or kind", len(objs))
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
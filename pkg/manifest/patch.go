@@ -0,0 +1,74 @@
+package manifest
+
+import (
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	// PatchTypeStrategic is a strategic merge patch.
+	PatchTypeStrategic = "strategic"
+
+	// PatchTypeJSON6902 is a JSON patch of RFC 6902 operations.
+	PatchTypeJSON6902 = "json6902"
+)
+
+// Patch will apply the YAML or JSON patch of the given type to the object,
+// returning the patched object. Strategic merge patches of kinds unknown to
+// client-go, such as custom resources, are applied as JSON merge patches.
+func Patch(obj *unstructured.Unstructured, patchType string, patch []byte) (*unstructured.Unstructured, error) {
+	patchJSON, err := utilyaml.ToJSON(patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse patch: %s", err)
+	}
+
+	original, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch patchType {
+	case PatchTypeStrategic:
+		dataStruct, err := scheme.Scheme.New(obj.GroupVersionKind())
+		switch {
+		case runtime.IsNotRegisteredError(err):
+			patched, err = jsonpatch.MergePatch(original, patchJSON)
+		case err != nil:
+			return nil, err
+		default:
+			patched, err = strategicpatch.StrategicMergePatch(original, patchJSON, dataStruct)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+	case PatchTypeJSON6902:
+		ops, err := jsonpatch.DecodePatch(patchJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode json6902 patch: %s", err)
+		}
+
+		patched, err = ops.Apply(original)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported patch type %q, must be one of [%s|%s]",
+			patchType, PatchTypeStrategic, PatchTypeJSON6902)
+	}
+
+	out := new(unstructured.Unstructured)
+	if err := out.UnmarshalJSON(patched); err != nil {
+		return nil, fmt.Errorf("failed to decode patched object: %s", err)
+	}
+
+	return out, nil
+}
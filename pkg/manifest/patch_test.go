@@ -0,0 +1,134 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func testDaemonSet() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "DaemonSet",
+		"metadata":   map[string]interface{}{"name": "cilium"},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "agent", "image": "cilium:1"},
+						map[string]interface{}{"name": "sidecar", "image": "busybox"},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func TestPatch(t *testing.T) {
+	containers := []string{"spec", "template", "spec", "containers"}
+
+	for _, test := range []struct {
+		name      string
+		obj       *unstructured.Unstructured
+		patchType string
+		patch     string
+		path      []string
+		want      interface{}
+		err       string
+	}{
+		{
+			name:      "strategic merges lists by key",
+			obj:       testDaemonSet(),
+			patchType: PatchTypeStrategic,
+			patch:     "spec:\n  template:\n    spec:\n      containers:\n      - name: agent\n        image: cilium:2\n",
+			path:      containers,
+			want: []interface{}{
+				map[string]interface{}{"name": "agent", "image": "cilium:2"},
+				map[string]interface{}{"name": "sidecar", "image": "busybox"},
+			},
+		},
+		{
+			name: "strategic falls back to merge patch for unregistered kinds",
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "cilium.io/v2",
+				"kind":       "CiliumNetworkPolicy",
+				"metadata":   map[string]interface{}{"name": "policy"},
+				"spec":       map[string]interface{}{"endpointSelector": map[string]interface{}{}, "ingress": []interface{}{"a", "b"}},
+			}},
+			patchType: PatchTypeStrategic,
+			patch:     "spec:\n  ingress:\n  - c\n",
+			path:      []string{"spec", "ingress"},
+			want:      []interface{}{"c"},
+		},
+		{
+			name:      "json6902",
+			obj:       testDaemonSet(),
+			patchType: PatchTypeJSON6902,
+			patch:     "- op: remove\n  path: /spec/template/spec/containers/1\n",
+			path:      containers,
+			want: []interface{}{
+				map[string]interface{}{"name": "agent", "image": "cilium:1"},
+			},
+		},
+		{
+			name:      "json6902 as json",
+			obj:       testDaemonSet(),
+			patchType: PatchTypeJSON6902,
+			patch:     `[{"op": "replace", "path": "/metadata/name", "value": "cilium-agent"}]`,
+			path:      []string{"metadata", "name"},
+			want:      "cilium-agent",
+		},
+		{
+			name:      "json6902 failing operation",
+			obj:       testDaemonSet(),
+			patchType: PatchTypeJSON6902,
+			patch:     "- op: remove\n  path: /spec/missing\n",
+			err:       "missing",
+		},
+		{
+			name:      "json6902 not a list of operations",
+			obj:       testDaemonSet(),
+			patchType: PatchTypeJSON6902,
+			patch:     "op: remove\n",
+			err:       "failed to decode json6902 patch",
+		},
+		{
+			name:      "invalid yaml",
+			obj:       testDaemonSet(),
+			patchType: PatchTypeStrategic,
+			patch:     "spec: [",
+			err:       "failed to parse patch",
+		},
+		{
+			name:      "unsupported type",
+			obj:       testDaemonSet(),
+			patchType: "merge",
+			patch:     "spec: {}\n",
+			err:       `unsupported patch type "merge"`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Patch(test.obj, test.patchType, []byte(test.patch))
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			field, _, err := unstructured.NestedFieldNoCopy(got.Object, test.path...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(field, test.want) {
+				t.Errorf("got %v, want %v", field, test.want)
+			}
+		})
+	}
+}
@@ -10,11 +10,23 @@ import (
 
 	"github.com/jetstack/cni-migration/pkg"
 	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/driver"
+	"github.com/jetstack/cni-migration/pkg/ledger"
 	"github.com/jetstack/cni-migration/pkg/util"
 )
 
 const (
-	ContextNodesKey = "cni-migration-migrate-nodes"
+	StepName               = "4-migrate"
+	ContextNodesKey        = "cni-migration-migrate-nodes"
+	ContextNodeSelectorKey = "cni-migration-migrate-node-selector"
+	ContextResumeKey       = "cni-migration-migrate-resume"
+
+	// ResumeFinish will finish the migration of partially migrated nodes.
+	ResumeFinish = "finish"
+
+	// ResumeRevert will revert partially migrated nodes to their state
+	// before migration.
+	ResumeRevert = "revert"
 )
 
 var _ pkg.Step = &Migrate{}
@@ -24,23 +36,28 @@ type Migrate struct {
 	log *logrus.Entry
 
 	config  *config.Config
-	client  *kubernetes.Clientset
+	client  kubernetes.Interface
 	factory *util.Factory
+	ledger  *ledger.Ledger
+	target  driver.CNIDriver
 }
 
 func New(ctx context.Context, config *config.Config) pkg.Step {
-	log := config.Log.WithField("step", "4-migrate")
+	log := config.Log.WithField("step", StepName)
 	return &Migrate{
 		log:     log,
 		ctx:     ctx,
 		config:  config,
 		client:  config.Client,
-		factory: util.New(ctx, log, config.Client),
+		factory: util.New(ctx, log, config),
+		ledger:  ledger.New(ctx, log, config.Client),
+		target:  driver.Target(ctx, log, config),
 	}
 }
 
 // Ready ensures that
 // - All nodes have the 'migrated' label
+// - No nodes are partially migrated
 func (m *Migrate) Ready() (bool, error) {
 	nodes, err := m.client.CoreV1().Nodes().List(m.ctx, metav1.ListOptions{})
 	if err != nil {
@@ -48,7 +65,7 @@ func (m *Migrate) Ready() (bool, error) {
 	}
 
 	for _, n := range nodes.Items {
-		if !m.hasRequiredLabel(n.Labels) {
+		if !m.hasRequiredLabel(n.Labels) || len(util.Checkpoint(&n, StepName)) > 0 {
 			return false, nil
 		}
 	}
@@ -59,7 +76,7 @@ func (m *Migrate) Ready() (bool, error) {
 }
 
 func (m *Migrate) Run(dryrun bool) error {
-	nodes, flagEnabled, err := util.NodesFromContext(m.client, m.ctx, ContextNodesKey)
+	nodes, flagEnabled, err := util.NodesFromContext(m.client, m.ctx, ContextNodesKey, ContextNodeSelectorKey)
 	if err != nil {
 		return err
 	}
@@ -75,107 +92,240 @@ func (m *Migrate) Run(dryrun bool) error {
 		nodes = nodesList.Items
 	}
 
+	var toProcess []corev1.Node
 	for _, node := range nodes {
-		m.log.Infof("migrating nodes %s...", node.Name)
-
-		if !m.hasRequiredLabel(node.Labels) {
-			if err := m.node(dryrun, node.Name); err != nil {
-				return err
-			}
+		if !m.hasRequiredLabel(node.Labels) || len(util.Checkpoint(&node, StepName)) > 0 {
+			toProcess = append(toProcess, node)
 		}
 	}
 
-	return nil
+	return m.factory.RunBatches(dryrun, toProcess, func(ctx context.Context, nodeName string) error {
+		m.log.Infof("migrating node %s...", nodeName)
+
+		return m.ledger.RecordNode(dryrun, StepName, nodeName, func() error {
+			return m.node(ctx, dryrun, nodeName)
+		})
+	})
 }
 
-func (m *Migrate) node(dryrun bool, nodeName string) error {
-	m.log.Infof("Draining node %s", nodeName)
+// Sub-steps of migrating a node, in order. The last completed sub-step is
+// checkpointed on the node, so that an interrupted migration can be resumed
+// without draining the node again.
+const (
+	subStepDrained     = "drained"
+	subStepTainted     = "tainted"
+	subStepPodsDeleted = "pods-deleted"
+	subStepUntainted   = "untainted"
+	subStepUncordoned  = "uncordoned"
+)
 
-	if !dryrun {
-		if err := m.factory.CheckKnetStress(); err != nil {
-			return err
+var subSteps = []string{
+	subStepDrained,
+	subStepTainted,
+	subStepPodsDeleted,
+	subStepUntainted,
+	subStepUncordoned,
+}
+
+func (m *Migrate) node(ctx context.Context, dryrun bool, nodeName string) error {
+	factory := m.factory.ForNode(ctx, nodeName)
+
+	node, err := m.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	resume := m.resumeFrom(node)
+	if resume > 0 {
+		m.log.Infof("node %s was partially migrated up to %s", nodeName, subSteps[resume-1])
+
+		// Nodes which only need labelling are always finished
+		if m.resumeStrategy() == ResumeRevert && resume < len(subSteps) {
+			return m.revert(ctx, factory, dryrun, nodeName)
 		}
 
-		args := []string{"kubectl", "drain", "--delete-local-data", "--ignore-daemonsets", nodeName}
-		if err := m.factory.RunCommand(nil, args...); err != nil {
+		m.log.Infof("resuming migration of node %s", nodeName)
+
+		// Ensure the node is still cordoned, without draining it again
+		if resume < len(subSteps) && !dryrun {
+			if err := factory.CordonNode(nodeName); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, subStep := range subSteps[resume:] {
+		if err := m.subStep(ctx, factory, dryrun, nodeName, subStep); err != nil {
 			return err
 		}
 
-		args = []string{"kubectl", "taint", "node", nodeName, "node-role.kubernetes.io/cilium=cilium:NoExecute", "--overwrite"}
-		if err := m.factory.RunCommand(nil, args...); err != nil {
+		if err := factory.SetCheckpoint(dryrun, nodeName, StepName, subStep); err != nil {
 			return err
 		}
 	}
 
-	// Add taint on node
-	m.log.Infof("Adding %s=%s:NoExecute taint to node %s ",
-		m.config.Labels.Cilium, m.config.Labels.Value, nodeName)
+	m.log.Infof("adding label %s=%s to node %s",
+		m.config.Labels.Migrated, m.config.Labels.Value, nodeName)
 	if !dryrun {
-		if err := m.addCiliumTaint(nodeName); err != nil {
+		if err := m.setNodeMigratedLabel(ctx, nodeName); err != nil {
+			return err
+		}
+
+		if err := factory.ClearCheckpoint(dryrun, nodeName); err != nil {
 			return err
 		}
 	}
 
-	m.log.Infof("removing pods on node %s", nodeName)
-	if !dryrun {
+	return nil
+}
 
-		if err := m.factory.WaitDaemonSetReady("kube-system", "cilium-migrated"); err != nil {
-			return err
+func (m *Migrate) subStep(ctx context.Context, factory *util.Factory, dryrun bool, nodeName, subStep string) error {
+	switch subStep {
+	case subStepDrained:
+		m.log.Infof("Draining node %s", nodeName)
+		if !dryrun {
+			if err := factory.DrainNode(nodeName); err != nil {
+				return err
+			}
 		}
 
-		// Delete all pods on that node
-		if err := m.factory.DeletePodsOnNode(nodeName); err != nil {
-			return err
+	case subStepTainted:
+		m.log.Infof("Adding %s=%s:NoExecute taint to node %s ",
+			m.config.Labels.Cilium, m.config.Labels.Value, nodeName)
+		if !dryrun {
+			if err := m.addCiliumTaint(ctx, nodeName); err != nil {
+				return err
+			}
 		}
 
-		if err := m.factory.WaitAllReady(m.config.WatchedResources); err != nil {
-			return err
+	case subStepPodsDeleted:
+		m.log.Infof("removing pods on node %s", nodeName)
+		if !dryrun {
+			if err := m.target.Ready(); err != nil {
+				return err
+			}
+
+			// Delete all pods on that node
+			if err := factory.DeletePodsOnNode(nodeName); err != nil {
+				return err
+			}
+
+			if err := factory.WaitAllReady(m.config.WatchedResources); err != nil {
+				return err
+			}
 		}
 
-		// Check knet connectivity
-		if err := m.factory.CheckKnetStress(); err != nil {
-			return err
+	case subStepUntainted:
+		m.log.Infof("removing %s=%s:NoExecute taint on node %s",
+			m.config.Labels.Cilium, m.config.Labels.Value, nodeName)
+		if !dryrun {
+			if err := m.deleteCiliumTaint(ctx, nodeName); err != nil {
+				return err
+			}
 		}
-	}
 
-	// Remove taint on node
-	m.log.Infof("removing %s=%s:NoExecute taint on node %s",
-		m.config.Labels.Cilium, m.config.Labels.Value, nodeName)
-	if !dryrun {
-		if err := m.deleteCiliumTaint(nodeName); err != nil {
-			return err
+	case subStepUncordoned:
+		m.log.Infof("uncordoning node %s", nodeName)
+		if !dryrun {
+			if err := factory.UncordonNode(nodeName); err != nil {
+				return err
+			}
+
+			if err := factory.WaitAllReady(m.config.WatchedResources); err != nil {
+				return err
+			}
 		}
 	}
 
-	m.log.Infof("uncordoning node %s", nodeName)
-	if !dryrun {
-		args := []string{"kubectl", "uncordon", nodeName}
-		if err := m.factory.RunCommand(nil, args...); err != nil {
-			return err
+	return nil
+}
+
+// resumeFrom returns the index of the first sub-step which has not been
+// completed on the node. Nodes without a checkpoint, but which have the Cilium
+// taint or label and are not migrated, were interrupted after being tainted.
+func (m *Migrate) resumeFrom(node *corev1.Node) int {
+	if checkpoint := util.Checkpoint(node, StepName); len(checkpoint) > 0 {
+		for i, subStep := range subSteps {
+			if subStep == checkpoint {
+				return i + 1
+			}
 		}
+	}
 
-		if err := m.factory.WaitAllReady(m.config.WatchedResources); err != nil {
-			return err
+	if m.hasRequiredLabel(node.Labels) {
+		return 0
+	}
+
+	_, hasLabel := node.Labels[m.config.Labels.Cilium]
+	if hasLabel || m.hasCiliumTaint(node) {
+		for i, subStep := range subSteps {
+			if subStep == subStepTainted {
+				return i + 1
+			}
 		}
 	}
 
-	m.log.Infof("adding label %s=% to node %s",
-		m.config.Labels.Migrated, m.config.Labels.Value, nodeName)
+	return 0
+}
+
+// resumeStrategy returns how partially migrated nodes should be handled.
+func (m *Migrate) resumeStrategy() string {
+	if s, ok := m.ctx.Value(ContextResumeKey).(string); ok && len(s) > 0 {
+		return s
+	}
+
+	return ResumeFinish
+}
+
+// revert will return a partially migrated node to its state before migration,
+// by removing the Cilium taint, restoring the labels and rolling the node. The
+// node will be migrated from the start on the next run.
+func (m *Migrate) revert(ctx context.Context, factory *util.Factory, dryrun bool, nodeName string) error {
+	m.log.Infof("reverting partial migration of node %s", nodeName)
+
 	if !dryrun {
-		if err := m.setNodeMigratedLabel(nodeName); err != nil {
+		if err := m.deleteCiliumTaint(ctx, nodeName); err != nil {
+			return err
+		}
+
+		node, err := m.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
 			return err
 		}
 
-		if err := m.factory.CheckKnetStress(); err != nil {
+		delete(node.Labels, m.config.Labels.Cilium)
+		node.Labels[m.config.Labels.CanalCilium] = m.config.Labels.Value
+
+		if _, err := m.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
 			return err
 		}
 	}
 
+	if err := factory.RollNode(dryrun, nodeName, m.config.WatchedResources); err != nil {
+		return err
+	}
+
+	if err := factory.ClearCheckpoint(dryrun, nodeName); err != nil {
+		return err
+	}
+
+	m.log.Infof("reverted node %s, re-run to migrate it", nodeName)
+
 	return nil
 }
 
-func (m *Migrate) deleteCiliumTaint(nodeName string) error {
-	node, err := m.client.CoreV1().Nodes().Get(m.ctx, nodeName, metav1.GetOptions{})
+func (m *Migrate) hasCiliumTaint(node *corev1.Node) bool {
+	for _, t := range node.Spec.Taints {
+		if t.Key == m.config.Labels.Cilium {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Migrate) deleteCiliumTaint(ctx context.Context, nodeName string) error {
+	node, err := m.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
@@ -188,7 +338,7 @@ func (m *Migrate) deleteCiliumTaint(nodeName string) error {
 	}
 	node.Spec.Taints = taints
 
-	_, err = m.client.CoreV1().Nodes().Update(m.ctx, node, metav1.UpdateOptions{})
+	_, err = m.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
 	if err != nil {
 		return err
 	}
@@ -196,8 +346,8 @@ func (m *Migrate) deleteCiliumTaint(nodeName string) error {
 	return nil
 }
 
-func (m *Migrate) addCiliumTaint(nodeName string) error {
-	node, err := m.client.CoreV1().Nodes().Get(m.ctx, nodeName, metav1.GetOptions{})
+func (m *Migrate) addCiliumTaint(ctx context.Context, nodeName string) error {
+	node, err := m.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
@@ -222,7 +372,7 @@ func (m *Migrate) addCiliumTaint(nodeName string) error {
 	delete(node.Labels, m.config.Labels.CanalCilium)
 	node.Labels[m.config.Labels.Cilium] = m.config.Labels.Value
 
-	node, err = m.client.CoreV1().Nodes().Update(m.ctx, node, metav1.UpdateOptions{})
+	node, err = m.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
 	if err != nil {
 		return err
 	}
@@ -230,8 +380,8 @@ func (m *Migrate) addCiliumTaint(nodeName string) error {
 	return nil
 }
 
-func (m *Migrate) setNodeMigratedLabel(nodeName string) error {
-	node, err := m.client.CoreV1().Nodes().Get(m.ctx, nodeName, metav1.GetOptions{})
+func (m *Migrate) setNodeMigratedLabel(ctx context.Context, nodeName string) error {
+	node, err := m.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
@@ -241,7 +391,7 @@ func (m *Migrate) setNodeMigratedLabel(nodeName string) error {
 	delete(node.Labels, m.config.Labels.CanalCilium)
 	node.Labels[m.config.Labels.Migrated] = m.config.Labels.Value
 
-	_, err = m.client.CoreV1().Nodes().Update(m.ctx, node, metav1.UpdateOptions{})
+	_, err = m.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
 	if err != nil {
 		return err
 	}
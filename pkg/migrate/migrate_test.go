@@ -0,0 +1,130 @@
+package migrate
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/rollback"
+	"github.com/jetstack/cni-migration/pkg/util"
+)
+
+const testConfig = `apiVersion: cni-migration.jetstack.io/v1alpha1
+kind: MigrationConfig
+labels:
+  canal-cilium: node-role.kubernetes.io/canal-cilium
+  cni-priority-canal: node-role.kubernetes.io/priority-canal
+  cni-priority-cilium: node-role.kubernetes.io/priority-cilium
+  rolled: node-role.kubernetes.io/rolled
+  cilium: node-role.kubernetes.io/cilium
+  migrated: node-role.kubernetes.io/migrated
+  value: "true"
+checks:
+  enabled: []
+`
+
+func testConfigFor(t *testing.T, objs ...runtime.Object) *config.Config {
+	file, err := ioutil.TempFile("", "cni-migration-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(testConfig); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	cfg, err := config.Read(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	cfg.Client = fake.NewSimpleClientset(objs...)
+	cfg.Log = logrus.NewEntry(logger)
+
+	return cfg
+}
+
+// TestResumeAfterRollback ensures a node which was rolled back after an
+// interrupted migration is migrated from the start, rather than resumed from
+// the stale checkpoint. Rollback.Run checks knet-stress connectivity, which
+// cannot be probed with a fake client, so the checkpoint is cleared as
+// rollback clears it.
+func TestResumeAfterRollback(t *testing.T) {
+	ctx := context.Background()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				"node-role.kubernetes.io/canal-cilium":   "true",
+				"node-role.kubernetes.io/priority-canal": "true",
+			},
+			Annotations: map[string]string{
+				util.CheckpointAnnotation: StepName + "/" + subStepDrained,
+			},
+		},
+	}
+
+	canal := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "kube-system",
+			Name:      "canal",
+		},
+	}
+
+	cfg := testConfigFor(t, node, canal)
+	m := New(ctx, cfg).(*Migrate)
+
+	if resume := m.resumeFrom(node); resume == 0 {
+		t.Fatalf("expected interrupted node to resume after %s, got start", subStepDrained)
+	}
+
+	rb := rollback.New(ctx, cfg)
+
+	ready, err := rb.Ready()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ready {
+		t.Errorf("expected node with a migration checkpoint to not be rolled back")
+	}
+
+	if err := util.New(ctx, cfg.Log, cfg).ClearCheckpoint(false, "node-1"); err != nil {
+		t.Fatalf("failed to clear checkpoint: %s", err)
+	}
+
+	node, err = cfg.Client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if checkpoint := util.Checkpoint(node, StepName); len(checkpoint) > 0 {
+		t.Errorf("expected checkpoint to be cleared, got %q", checkpoint)
+	}
+
+	if resume := m.resumeFrom(node); resume != 0 {
+		t.Errorf("expected rolled back node to be migrated from the start, got resume from %s", subSteps[resume-1])
+	}
+
+	ready, err = rb.Ready()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ready {
+		t.Errorf("expected node without a migration checkpoint to be rolled back")
+	}
+}
@@ -2,6 +2,8 @@ package preflight
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
@@ -10,6 +12,12 @@ import (
 	"github.com/jetstack/cni-migration/pkg/util"
 )
 
+const (
+	StepName = "0-preflight"
+
+	ContextAllowPDBViolationsKey = "cni-migration-preflight-allow-pdb-violations"
+)
+
 var _ pkg.Step = &Preflight{}
 
 type Preflight struct {
@@ -21,12 +29,12 @@ type Preflight struct {
 }
 
 func New(ctx context.Context, config *config.Config) pkg.Step {
-	log := config.Log.WithField("step", "0-preflight")
+	log := config.Log.WithField("step", StepName)
 	return &Preflight{
 		ctx:     ctx,
 		log:     log,
 		config:  config,
-		factory: util.New(ctx, log, config.Client),
+		factory: util.New(ctx, log, config),
 	}
 }
 
@@ -39,11 +47,16 @@ func (p *Preflight) Ready() (bool, error) {
 		return false, err
 	}
 
-	if err := p.factory.WaitDaemonSetReady("knet-stress", "knet-stress"); err != nil {
+	hasNetworkPolicy, err := p.factory.HasNetworkPolicy()
+	if err != nil || !hasNetworkPolicy {
 		return false, err
 	}
 
-	if err := p.factory.CheckKnetStress(); err != nil {
+	if err := p.factory.WaitKnetStressReady(); err != nil {
+		return false, err
+	}
+
+	if err := p.factory.CheckConnectivity(); err != nil {
 		return false, err
 	}
 
@@ -53,11 +66,22 @@ func (p *Preflight) Ready() (bool, error) {
 }
 
 // Run will ensure that
+// - The cluster meets the prerequisites of the migration
+// - No workloads will block draining nodes
 // - Knet-stress is deployed
+// - The network policy bundle is deployed, if its check is enabled
 // - Knet-stress is healty
 func (p *Preflight) Run(dryrun bool) error {
 	p.log.Infof("running preflight checks...")
 
+	if err := p.checkPrerequisites(dryrun); err != nil {
+		return err
+	}
+
+	if err := p.checkDisruptions(); err != nil {
+		return err
+	}
+
 	requiredResources, err := p.factory.Has(p.config.PreflightResources)
 	if err != nil {
 		return err
@@ -65,18 +89,99 @@ func (p *Preflight) Run(dryrun bool) error {
 
 	if !requiredResources {
 		p.log.Infof("creating knet-stress resources")
-		if !dryrun {
-			if err := p.factory.CreateDaemonSet(p.config.Paths.KnetStress, "knet-stress", "knet-stress"); err != nil {
-				return err
-			}
+		if err := p.factory.CreateResource(dryrun, p.config.Paths.KnetStress, p.config.KnetStress.Namespace); err != nil {
+			return err
+		}
+	}
+
+	hasNetworkPolicy, err := p.factory.HasNetworkPolicy()
+	if err != nil {
+		return err
+	}
+
+	if !hasNetworkPolicy {
+		if err := p.factory.CreateNetworkPolicy(dryrun); err != nil {
+			return err
 		}
 	}
 
 	if !dryrun {
-		if err := p.factory.CheckKnetStress(); err != nil {
+		if err := p.factory.CheckConnectivity(); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// checkPrerequisites will check the cluster meets the prerequisites of the
+// migration, failing if any check does not pass.
+func (p *Preflight) checkPrerequisites(dryrun bool) error {
+	p.log.Infof("checking cluster prerequisites...")
+
+	report, err := p.factory.CheckPrerequisites(dryrun)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster prerequisites: %s", err)
+	}
+
+	for _, r := range report.Results {
+		if r.Passed {
+			p.log.Infof("prerequisite %s %s: %s", r.Check, r.Node, r.Message)
+		} else {
+			p.log.Errorf("prerequisite %s %s: %s", r.Check, r.Node, r.Message)
+		}
+	}
+
+	if path := p.config.Prerequisites.ReportPath; len(path) > 0 {
+		if err := report.WriteFile(path); err != nil {
+			return fmt.Errorf("failed to write prerequisites report %q: %s", path, err)
+		}
+		p.log.Infof("prerequisites report written to %s", path)
+	}
+
+	if failed := report.Failed(); len(failed) > 0 {
+		return fmt.Errorf("%d of %d cluster prerequisite checks failed", len(failed), len(report.Results))
+	}
+
+	p.log.Infof("all %d cluster prerequisite checks passed", len(report.Results))
+
+	return nil
+}
+
+// checkDisruptions will report all workloads which would block, or lose
+// availability, whilst nodes are drained. Blocking workloads fail preflight
+// unless violations have been allowed.
+func (p *Preflight) checkDisruptions() error {
+	p.log.Infof("checking pod disruption budgets...")
+
+	issues, err := p.factory.AnalyzeDisruptions()
+	if err != nil {
+		return fmt.Errorf("failed to analyze pod disruption budgets: %s", err)
+	}
+
+	var blocking []string
+	for _, issue := range issues {
+		nodes := strings.Join(issue.Nodes, ", ")
+		if issue.Blocking {
+			p.log.Errorf("%s %s/%s will block draining nodes [%s]: %s",
+				issue.Kind, issue.Namespace, issue.Name, nodes, issue.Reason)
+			blocking = append(blocking, issue.Kind+" "+issue.Namespace+"/"+issue.Name)
+		} else {
+			p.log.Warnf("%s %s/%s will lose availability whilst draining nodes [%s]: %s",
+				issue.Kind, issue.Namespace, issue.Name, nodes, issue.Reason)
+		}
+	}
+
+	if len(blocking) == 0 {
+		p.log.Infof("no workloads will block draining nodes")
+		return nil
+	}
+
+	if allow, _ := p.ctx.Value(ContextAllowPDBViolationsKey).(bool); allow {
+		p.log.Warnf("ignoring %d workloads which will block draining nodes", len(blocking))
+		return nil
+	}
+
+	return fmt.Errorf("workloads will block draining nodes, use --allow-pdb-violations to continue: %s",
+		strings.Join(blocking, ", "))
+}
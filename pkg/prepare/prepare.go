@@ -9,9 +9,14 @@ import (
 
 	"github.com/jetstack/cni-migration/pkg"
 	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/driver"
 	"github.com/jetstack/cni-migration/pkg/util"
 )
 
+const (
+	StepName = "1-prepare"
+)
+
 var _ pkg.Step = &Prepare{}
 
 type Prepare struct {
@@ -19,25 +24,29 @@ type Prepare struct {
 	log *logrus.Entry
 
 	config  *config.Config
-	client  *kubernetes.Clientset
+	client  kubernetes.Interface
 	factory *util.Factory
+	source  driver.CNIDriver
+	target  driver.CNIDriver
 }
 
 func New(ctx context.Context, config *config.Config) pkg.Step {
-	log := config.Log.WithField("step", "1-prepare")
+	log := config.Log.WithField("step", StepName)
 	return &Prepare{
 		log:     log,
 		ctx:     ctx,
 		config:  config,
 		client:  config.Client,
-		factory: util.New(ctx, log, config.Client),
+		factory: util.New(ctx, log, config),
+		source:  driver.Source(ctx, log, config),
+		target:  driver.Target(ctx, log, config),
 	}
 }
 
 // Ready ensures that
 // - Nodes have correct labels
 // - The required resources exist
-// - Canal DaemonSet has been patched
+// - Source CNI DaemonSet has been patched
 func (p *Prepare) Ready() (bool, error) {
 	nodes, err := p.client.CoreV1().Nodes().List(p.ctx, metav1.ListOptions{})
 	if err != nil {
@@ -50,7 +59,7 @@ func (p *Prepare) Ready() (bool, error) {
 		}
 	}
 
-	patched, err := p.canalIsPatched()
+	patched, err := p.source.SelectorPatched(p.config.Labels.CanalCilium, p.config.Labels.Value)
 	if err != nil || !patched {
 		return false, err
 	}
@@ -68,7 +77,7 @@ func (p *Prepare) Ready() (bool, error) {
 // Run will ensure that
 // - Node have correct labels
 // - The required resources exist
-// - Canal DaemonSet has been patched
+// - Source CNI DaemonSet has been patched
 func (p *Prepare) Run(dryrun bool) error {
 	p.log.Infof("preparing migration...")
 
@@ -98,17 +107,21 @@ func (p *Prepare) Run(dryrun bool) error {
 		}
 	}
 
-	patched, err := p.canalIsPatched()
+	if err := p.source.Install(dryrun); err != nil {
+		return err
+	}
+
+	patched, err := p.source.SelectorPatched(p.config.Labels.CanalCilium, p.config.Labels.Value)
 	if err != nil {
 		return err
 	}
 
 	if !patched {
-		p.log.Infof("patching canal DaemonSet with node selector %s=%s",
-			p.config.Labels.CanalCilium, p.config.Labels.Value)
+		p.log.Infof("patching %s DaemonSet with node selector %s=%s",
+			p.source.Name(), p.config.Labels.CanalCilium, p.config.Labels.Value)
 
 		if !dryrun {
-			if err := p.patchCanal(); err != nil {
+			if err := p.source.PatchSelector(p.config.Labels.CanalCilium, p.config.Labels.Value); err != nil {
 				return err
 			}
 		}
@@ -120,18 +133,14 @@ func (p *Prepare) Run(dryrun bool) error {
 	}
 
 	if !requiredResources {
-		p.log.Infof("creating cilium resources")
-		if !dryrun {
-			if err := p.factory.CreateDaemonSet(p.config.Paths.Cilium, "kube-system", "cilium"); err != nil {
-				return err
-			}
+		p.log.Infof("creating %s resources", p.target.Name())
+		if err := p.target.Install(dryrun); err != nil {
+			return err
 		}
 
 		p.log.Infof("creating multus resources")
-		if !dryrun {
-			if err := p.factory.CreateDaemonSet(p.config.Paths.Multus, "kube-system", "kube-multus-canal"); err != nil {
-				return err
-			}
+		if err := p.factory.CreateResource(dryrun, p.config.Paths.Multus, "kube-system"); err != nil {
+			return err
 		}
 	}
 
@@ -140,7 +149,7 @@ func (p *Prepare) Run(dryrun bool) error {
 			return err
 		}
 
-		if err := p.factory.CheckKnetStress(); err != nil {
+		if err := p.factory.CheckConnectivity(); err != nil {
 			return err
 		}
 	}
@@ -148,25 +157,6 @@ func (p *Prepare) Run(dryrun bool) error {
 	return nil
 }
 
-func (p *Prepare) patchCanal() error {
-	ds, err := p.client.AppsV1().DaemonSets("kube-system").Get(p.ctx, "canal", metav1.GetOptions{})
-	if err != nil {
-		return err
-	}
-
-	if ds.Spec.Template.Spec.NodeSelector == nil {
-		ds.Spec.Template.Spec.NodeSelector = make(map[string]string)
-	}
-	ds.Spec.Template.Spec.NodeSelector[p.config.Labels.CanalCilium] = p.config.Labels.Value
-
-	_, err = p.client.AppsV1().DaemonSets("kube-system").Update(p.ctx, ds, metav1.UpdateOptions{})
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func (p *Prepare) hasRequiredLabel(labels map[string]string) bool {
 	if labels == nil {
 		return false
@@ -203,19 +193,3 @@ func (p *Prepare) hasRequiredLabel(labels map[string]string) bool {
 
 	return true
 }
-
-func (p *Prepare) canalIsPatched() (bool, error) {
-	ds, err := p.client.AppsV1().DaemonSets("kube-system").Get(p.ctx, "canal", metav1.GetOptions{})
-	if err != nil {
-		return false, err
-	}
-
-	if ds.Spec.Template.Spec.NodeSelector == nil {
-		return false, nil
-	}
-	if v, ok := ds.Spec.Template.Spec.NodeSelector[p.config.Labels.CanalCilium]; !ok || v != p.config.Labels.Value {
-		return false, nil
-	}
-
-	return true, nil
-}
@@ -4,16 +4,20 @@ import (
 	"context"
 
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/jetstack/cni-migration/pkg"
 	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/ledger"
 	"github.com/jetstack/cni-migration/pkg/util"
 )
 
 const (
-	ContextNodesKey = "cni-migration-priority-nodes"
+	StepName               = "3-priority"
+	ContextNodesKey        = "cni-migration-priority-nodes"
+	ContextNodeSelectorKey = "cni-migration-priority-node-selector"
 )
 
 var _ pkg.Step = &Priority{}
@@ -23,18 +27,20 @@ type Priority struct {
 	log *logrus.Entry
 
 	config  *config.Config
-	client  *kubernetes.Clientset
+	client  kubernetes.Interface
 	factory *util.Factory
+	ledger  *ledger.Ledger
 }
 
 func New(ctx context.Context, config *config.Config) pkg.Step {
-	log := config.Log.WithField("step", "3-priority")
+	log := config.Log.WithField("step", StepName)
 	return &Priority{
 		log:     log,
 		ctx:     ctx,
 		config:  config,
 		client:  config.Client,
-		factory: util.New(ctx, log, config.Client),
+		factory: util.New(ctx, log, config),
+		ledger:  ledger.New(ctx, log, config.Client),
 	}
 }
 
@@ -58,13 +64,7 @@ func (p *Priority) Ready() (bool, error) {
 }
 
 func (p *Priority) Run(dryrun bool) error {
-	if !dryrun {
-		if err := p.factory.CheckKnetStress(); err != nil {
-			return err
-		}
-	}
-
-	nodes, flagEnabled, err := util.NodesFromContext(p.client, p.ctx, ContextNodesKey)
+	nodes, flagEnabled, err := util.NodesFromContext(p.client, p.ctx, ContextNodesKey, ContextNodeSelectorKey)
 	if err != nil {
 		return err
 	}
@@ -80,20 +80,26 @@ func (p *Priority) Run(dryrun bool) error {
 		nodes = nodesList.Items
 	}
 
+	var toProcess []corev1.Node
 	for _, node := range nodes {
 		if !p.hasRequiredLabel(node.Labels) {
-			p.log.Infof("changing CNI priority to Cilium on node %s", node.Name)
-			if err := p.node(dryrun, node.Name); err != nil {
-				return err
-			}
+			toProcess = append(toProcess, node)
 		}
 	}
 
-	return nil
+	return p.factory.RunBatches(dryrun, toProcess, func(ctx context.Context, nodeName string) error {
+		p.log.Infof("changing CNI priority to Cilium on node %s", nodeName)
+
+		return p.ledger.RecordNode(dryrun, StepName, nodeName, func() error {
+			return p.node(ctx, dryrun, nodeName)
+		})
+	})
 }
 
-func (p *Priority) node(dryrun bool, name string) error {
-	node, err := p.client.CoreV1().Nodes().Get(p.ctx, name, metav1.GetOptions{})
+func (p *Priority) node(ctx context.Context, dryrun bool, name string) error {
+	factory := p.factory.ForNode(ctx, name)
+
+	node, err := p.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
@@ -106,14 +112,14 @@ func (p *Priority) node(dryrun bool, name string) error {
 		delete(node.Labels, p.config.Labels.CNIPriorityCanal)
 		node.Labels[p.config.Labels.CNIPriorityCilium] = p.config.Labels.Value
 
-		_, err = p.client.CoreV1().Nodes().Update(p.ctx, node, metav1.UpdateOptions{})
+		_, err = p.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
 		if err != nil {
 			return err
 		}
 
 	}
 
-	if err := p.factory.RollNode(dryrun, name, p.config.WatchedResources); err != nil {
+	if err := factory.RollNode(dryrun, name, p.config.WatchedResources); err != nil {
 		return err
 	}
 
@@ -4,16 +4,20 @@ import (
 	"context"
 
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/jetstack/cni-migration/pkg"
 	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/ledger"
 	"github.com/jetstack/cni-migration/pkg/util"
 )
 
 const (
-	ContextNodesKey = "cni-migration-roll-nodes"
+	StepName               = "2-roll"
+	ContextNodesKey        = "cni-migration-roll-nodes"
+	ContextNodeSelectorKey = "cni-migration-roll-node-selector"
 )
 
 var _ pkg.Step = &Roll{}
@@ -23,18 +27,20 @@ type Roll struct {
 	log *logrus.Entry
 
 	config  *config.Config
-	client  *kubernetes.Clientset
+	client  kubernetes.Interface
 	factory *util.Factory
+	ledger  *ledger.Ledger
 }
 
 func New(ctx context.Context, config *config.Config) pkg.Step {
-	log := config.Log.WithField("step", "2-roll")
+	log := config.Log.WithField("step", StepName)
 	return &Roll{
 		log:     log,
 		ctx:     ctx,
 		config:  config,
 		client:  config.Client,
-		factory: util.New(ctx, log, config.Client),
+		factory: util.New(ctx, log, config),
+		ledger:  ledger.New(ctx, log, config.Client),
 	}
 }
 
@@ -58,7 +64,7 @@ func (r *Roll) Ready() (bool, error) {
 }
 
 func (r *Roll) Run(dryrun bool) error {
-	nodes, flagEnabled, err := util.NodesFromContext(r.client, r.ctx, ContextNodesKey)
+	nodes, flagEnabled, err := util.NodesFromContext(r.client, r.ctx, ContextNodesKey, ContextNodeSelectorKey)
 	if err != nil {
 		return err
 	}
@@ -74,32 +80,30 @@ func (r *Roll) Run(dryrun bool) error {
 		nodes = nodesList.Items
 	}
 
+	var toProcess []corev1.Node
 	for _, node := range nodes {
 		if !r.hasRequiredLabel(node.Labels) {
-			r.log.Infof("rolling node: %s", node.Name)
-
-			if err := r.node(dryrun, node.Name); err != nil {
-				return err
-			}
-
+			toProcess = append(toProcess, node)
 		}
 	}
 
-	return nil
+	return r.factory.RunBatches(dryrun, toProcess, func(ctx context.Context, nodeName string) error {
+		r.log.Infof("rolling node: %s", nodeName)
+
+		return r.ledger.RecordNode(dryrun, StepName, nodeName, func() error {
+			return r.node(ctx, dryrun, nodeName)
+		})
+	})
 }
 
-func (r *Roll) node(dryrun bool, name string) error {
-	if !dryrun {
-		if err := r.factory.CheckKnetStress(); err != nil {
-			return err
-		}
-	}
+func (r *Roll) node(ctx context.Context, dryrun bool, name string) error {
+	factory := r.factory.ForNode(ctx, name)
 
-	if err := r.factory.RollNode(dryrun, name, r.config.WatchedResources); err != nil {
+	if err := factory.RollNode(dryrun, name, r.config.WatchedResources); err != nil {
 		return err
 	}
 
-	node, err := r.client.CoreV1().Nodes().Get(r.ctx, name, metav1.GetOptions{})
+	node, err := r.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
@@ -111,7 +115,7 @@ func (r *Roll) node(dryrun bool, name string) error {
 		}
 		node.Labels[r.config.Labels.Rolled] = r.config.Labels.Value
 
-		_, err = r.client.CoreV1().Nodes().Update(r.ctx, node, metav1.UpdateOptions{})
+		_, err = r.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,285 @@
+package rollback
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/jetstack/cni-migration/pkg"
+	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/driver"
+	"github.com/jetstack/cni-migration/pkg/ledger"
+	"github.com/jetstack/cni-migration/pkg/status"
+	"github.com/jetstack/cni-migration/pkg/util"
+)
+
+const (
+	StepName               = "rollback"
+	ContextNodesKey        = "cni-migration-rollback-nodes"
+	ContextNodeSelectorKey = "cni-migration-rollback-node-selector"
+)
+
+var _ pkg.Step = &Rollback{}
+
+type Rollback struct {
+	ctx context.Context
+	log *logrus.Entry
+
+	config  *config.Config
+	client  kubernetes.Interface
+	factory *util.Factory
+	ledger  *ledger.Ledger
+	source  driver.CNIDriver
+}
+
+func New(ctx context.Context, config *config.Config) pkg.Step {
+	log := config.Log.WithField("step", StepName)
+	return &Rollback{
+		log:     log,
+		ctx:     ctx,
+		config:  config,
+		client:  config.Client,
+		factory: util.New(ctx, log, config),
+		ledger:  ledger.New(ctx, log, config.Client),
+		source:  driver.Source(ctx, log, config),
+	}
+}
+
+// Ready ensures that
+// - All nodes are using Canal as the primary CNI, and have not been rolled
+// - The source CNI DaemonSet node selector has been restored
+func (r *Rollback) Ready() (bool, error) {
+	nodes, err := r.client.CoreV1().Nodes().List(r.ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	for _, n := range nodes.Items {
+		if !r.isRolledBack(&n) {
+			return false, nil
+		}
+	}
+
+	restored, err := r.sourceIsRestored()
+	if err != nil || !restored {
+		return false, err
+	}
+
+	r.log.Info("rollback ready")
+
+	return true, nil
+}
+
+// Run will, for each node
+// - Clear the checkpoint of an interrupted migration
+// - Relabel the node to use Canal as the primary CNI
+// - Remove the Cilium taint
+// - Roll the node
+// - Remove the rolled label
+// Once all nodes have been rolled back, the original source CNI DaemonSet
+// node selector is restored.
+func (r *Rollback) Run(dryrun bool) error {
+	installed, err := r.source.Installed()
+	if err != nil {
+		return err
+	}
+
+	if !installed {
+		return fmt.Errorf("%s not installed, cannot roll back a cluster which has been cleaned up", r.source.Name())
+	}
+
+	if !dryrun {
+		if err := r.factory.CheckConnectivity(); err != nil {
+			return err
+		}
+	}
+
+	nodes, flagEnabled, err := util.NodesFromContext(r.client, r.ctx, ContextNodesKey, ContextNodeSelectorKey)
+	if err != nil {
+		return err
+	}
+
+	if !flagEnabled {
+		r.log.Info("rolling back all nodes...")
+
+		nodesList, err := r.client.CoreV1().Nodes().List(r.ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+
+		nodes = nodesList.Items
+	}
+
+	// Nodes which have been rolled back by this run. During a dry run these
+	// nodes will not have been updated.
+	rolledBack := make(map[string]bool)
+
+	for _, node := range nodes {
+		if !r.isRolledBack(&node) {
+			r.log.Infof("rolling back node %s (%s)", node.Name, status.NodePhase(r.config.Labels, &node))
+
+			err := r.ledger.RecordNode(dryrun, StepName, node.Name, func() error {
+				return util.WithTimeout(r.ctx, r.config.Timeouts.Node, "node operation on node "+node.Name, func(ctx context.Context) error {
+					return r.node(ctx, dryrun, node.Name)
+				})
+			})
+			if err != nil {
+				return err
+			}
+
+			rolledBack[node.Name] = true
+		}
+	}
+
+	nodesList, err := r.client.CoreV1().Nodes().List(r.ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, n := range nodesList.Items {
+		if !r.isRolledBack(&n) && !rolledBack[n.Name] {
+			r.log.Infof("not all nodes have been rolled back, not restoring %s node selector", r.source.Name())
+			return nil
+		}
+	}
+
+	restored, err := r.sourceIsRestored()
+	if err != nil {
+		return err
+	}
+
+	if !restored {
+		r.log.Infof("restoring %s DaemonSet node selector", r.source.Name())
+		if !dryrun {
+			if err := r.source.RestoreSelector(r.config.Labels.CanalCilium); err != nil {
+				return err
+			}
+
+			if err := r.source.Ready(); err != nil {
+				return err
+			}
+
+			if err := r.factory.CheckConnectivity(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Rollback) node(ctx context.Context, dryrun bool, nodeName string) error {
+	factory := r.factory.ForNode(ctx, nodeName)
+
+	// Clear the checkpoint of an interrupted migration first, so the node is
+	// migrated from the start on the next run rather than resumed.
+	r.log.Infof("clearing migration checkpoint of node %s", nodeName)
+	if err := factory.ClearCheckpoint(dryrun, nodeName); err != nil {
+		return err
+	}
+
+	r.log.Infof("relabelling node %s to use Canal as the primary CNI", nodeName)
+	if !dryrun {
+		err := r.updateNode(ctx, nodeName, func(node *corev1.Node) {
+			delete(node.Labels, r.config.Labels.Cilium)
+			delete(node.Labels, r.config.Labels.Migrated)
+			delete(node.Labels, r.config.Labels.CNIPriorityCilium)
+			node.Labels[r.config.Labels.CanalCilium] = r.config.Labels.Value
+			node.Labels[r.config.Labels.CNIPriorityCanal] = r.config.Labels.Value
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	r.log.Infof("removing %s taint on node %s", r.config.Labels.Cilium, nodeName)
+	if !dryrun {
+		err := r.updateNode(ctx, nodeName, func(node *corev1.Node) {
+			var taints []corev1.Taint
+			for _, t := range node.Spec.Taints {
+				if t.Key != r.config.Labels.Cilium {
+					taints = append(taints, t)
+				}
+			}
+			node.Spec.Taints = taints
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := factory.RollNode(dryrun, nodeName, r.config.WatchedResources); err != nil {
+		return err
+	}
+
+	// Nodes are rolled back one at a time, outside of batches.
+	if !dryrun {
+		if err := factory.CheckConnectivity(); err != nil {
+			return err
+		}
+	}
+
+	r.log.Infof("removing rolled label from node %s", nodeName)
+	if !dryrun {
+		err := r.updateNode(ctx, nodeName, func(node *corev1.Node) {
+			delete(node.Labels, r.config.Labels.Rolled)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Rollback) updateNode(ctx context.Context, nodeName string, mutate func(*corev1.Node)) error {
+	node, err := r.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if node.Labels == nil {
+		node.Labels = make(map[string]string)
+	}
+	mutate(node)
+
+	_, err = r.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// sourceIsRestored returns true if the source CNI DaemonSet node selector has
+// been restored.
+func (r *Rollback) sourceIsRestored() (bool, error) {
+	patched, err := r.source.SelectorPatched(r.config.Labels.CanalCilium, r.config.Labels.Value)
+	if err != nil {
+		return false, err
+	}
+
+	return !patched, nil
+}
+
+// isRolledBack returns true if the node is using Canal as the primary CNI, has
+// not been rolled, and has no checkpoint of an interrupted migration.
+func (r *Rollback) isRolledBack(node *corev1.Node) bool {
+	if _, ok := node.Labels[r.config.Labels.Rolled]; ok {
+		return false
+	}
+	if _, ok := node.Annotations[util.CheckpointAnnotation]; ok {
+		return false
+	}
+
+	switch status.NodePhase(r.config.Labels, node) {
+	case status.PhaseCanalPrimary, status.PhaseUnprepared:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,197 @@
+package status
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+// Phase is the migration phase a node is in.
+type Phase string
+
+const (
+	PhaseUnprepared    Phase = "unprepared"
+	PhaseCanalPrimary  Phase = "canal-primary"
+	PhaseRolled        Phase = "rolled"
+	PhaseCiliumPrimary Phase = "cilium-primary"
+	PhaseMigrating     Phase = "migrating"
+	PhaseMigrated      Phase = "migrated"
+)
+
+type NodeStatus struct {
+	Name     string `json:"name" yaml:"name"`
+	Phase    Phase  `json:"phase" yaml:"phase"`
+	Cordoned bool   `json:"cordoned" yaml:"cordoned"`
+	Tainted  bool   `json:"tainted" yaml:"tainted"`
+
+	// DaemonSets are the watched DaemonSets with a ready pod on this node.
+	DaemonSets []string `json:"daemonsets" yaml:"daemonsets"`
+}
+
+type DaemonSetStatus struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name" yaml:"name"`
+	Found     bool   `json:"found" yaml:"found"`
+	Desired   int32  `json:"desired" yaml:"desired"`
+	Ready     int32  `json:"ready" yaml:"ready"`
+	Updated   int32  `json:"updated" yaml:"updated"`
+}
+
+type Status struct {
+	Nodes      []NodeStatus      `json:"nodes" yaml:"nodes"`
+	DaemonSets []DaemonSetStatus `json:"daemonsets" yaml:"daemonsets"`
+}
+
+// Get computes the migration status of every node, and the state of every
+// watched DaemonSet, in the cluster.
+func Get(ctx context.Context, config *config.Config) (*Status, error) {
+	nodes, err := config.Client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	dsPods, err := readyDaemonSetPods(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	status := new(Status)
+	for _, node := range nodes.Items {
+		status.Nodes = append(status.Nodes, NodeStatus{
+			Name:       node.Name,
+			Phase:      NodePhase(config.Labels, &node),
+			Cordoned:   node.Spec.Unschedulable,
+			Tainted:    hasTaint(config.Labels, &node),
+			DaemonSets: dsPods[node.Name],
+		})
+	}
+
+	sort.Slice(status.Nodes, func(i, j int) bool {
+		return status.Nodes[i].Name < status.Nodes[j].Name
+	})
+
+	for _, namespace := range sortedKeys(config.WatchedResources.DaemonSets) {
+		for _, name := range config.WatchedResources.DaemonSets[namespace] {
+			dsStatus := DaemonSetStatus{
+				Namespace: namespace,
+				Name:      name,
+			}
+
+			ds, err := config.Client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+
+			if err == nil {
+				dsStatus.Found = true
+				dsStatus.Desired = ds.Status.DesiredNumberScheduled
+				dsStatus.Ready = ds.Status.NumberReady
+				dsStatus.Updated = ds.Status.UpdatedNumberScheduled
+			}
+
+			status.DaemonSets = append(status.DaemonSets, dsStatus)
+		}
+	}
+
+	return status, nil
+}
+
+// NodePhase returns the migration phase of the node, computed from its labels
+// and taints.
+func NodePhase(labels *config.Labels, node *corev1.Node) Phase {
+	has := func(key string) bool {
+		v, ok := node.Labels[key]
+		return ok && v == labels.Value
+	}
+
+	switch {
+	case has(labels.Migrated):
+		return PhaseMigrated
+
+	case has(labels.Cilium), hasTaint(labels, node):
+		return PhaseMigrating
+
+	case has(labels.CNIPriorityCilium):
+		return PhaseCiliumPrimary
+
+	case has(labels.CanalCilium) && has(labels.CNIPriorityCanal) && has(labels.Rolled):
+		return PhaseRolled
+
+	case has(labels.CanalCilium) && has(labels.CNIPriorityCanal):
+		return PhaseCanalPrimary
+
+	default:
+		return PhaseUnprepared
+	}
+}
+
+func hasTaint(labels *config.Labels, node *corev1.Node) bool {
+	for _, t := range node.Spec.Taints {
+		if t.Key == labels.Cilium {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readyDaemonSetPods returns the watched DaemonSets which have a ready pod,
+// keyed by node name.
+func readyDaemonSetPods(ctx context.Context, config *config.Config) (map[string][]string, error) {
+	dsPods := make(map[string][]string)
+
+	for _, namespace := range sortedKeys(config.WatchedResources.DaemonSets) {
+		watched := make(map[string]bool)
+		for _, name := range config.WatchedResources.DaemonSets[namespace] {
+			watched[name] = true
+		}
+
+		pods, err := config.Client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pod := range pods.Items {
+			controller := metav1.GetControllerOf(&pod)
+			if controller == nil || controller.Kind != "DaemonSet" || !watched[controller.Name] {
+				continue
+			}
+
+			if !podReady(&pod) {
+				continue
+			}
+
+			dsPods[pod.Spec.NodeName] = append(dsPods[pod.Spec.NodeName], namespace+"/"+controller.Name)
+		}
+	}
+
+	for _, names := range dsPods {
+		sort.Strings(names)
+	}
+
+	return dsPods, nil
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+func sortedKeys(m map[string][]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,71 @@
+package status
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+func TestNodePhase(t *testing.T) {
+	labels := &config.Labels{
+		CanalCilium:       "canal-cilium",
+		Rolled:            "rolled",
+		CNIPriorityCanal:  "cni-priority-canal",
+		CNIPriorityCilium: "cni-priority-cilium",
+		Cilium:            "cilium",
+		Migrated:          "migrated",
+		Value:             "true",
+	}
+
+	for _, test := range []struct {
+		name   string
+		labels []string
+		taint  bool
+		want   Phase
+	}{
+		{"no labels", nil, false, PhaseUnprepared},
+		{"canal-cilium only", []string{labels.CanalCilium}, false, PhaseUnprepared},
+		{"canal primary", []string{labels.CanalCilium, labels.CNIPriorityCanal}, false, PhaseCanalPrimary},
+		{"rolled", []string{labels.CanalCilium, labels.CNIPriorityCanal, labels.Rolled}, false, PhaseRolled},
+		{"rolled without canal-cilium", []string{labels.CNIPriorityCanal, labels.Rolled}, false, PhaseUnprepared},
+		{"cilium primary", []string{labels.CanalCilium, labels.CNIPriorityCilium}, false, PhaseCiliumPrimary},
+		{"cilium label", []string{labels.CanalCilium, labels.CNIPriorityCilium, labels.Cilium}, false, PhaseMigrating},
+		{"cilium taint", []string{labels.CanalCilium, labels.CNIPriorityCanal}, true, PhaseMigrating},
+		{"migrated", []string{labels.Cilium, labels.Migrated}, false, PhaseMigrated},
+		{"migrated and tainted", []string{labels.Migrated}, true, PhaseMigrated},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "node-1",
+					Labels: make(map[string]string),
+				},
+			}
+			for _, l := range test.labels {
+				node.Labels[l] = labels.Value
+			}
+			if test.taint {
+				node.Spec.Taints = []corev1.Taint{{Key: labels.Cilium, Effect: corev1.TaintEffectNoExecute}}
+			}
+
+			if got := NodePhase(labels, node); got != test.want {
+				t.Errorf("got phase %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	t.Run("label with other value is ignored", func(t *testing.T) {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{labels.Migrated: "false"},
+			},
+		}
+
+		if got := NodePhase(labels, node); got != PhaseUnprepared {
+			t.Errorf("got phase %q, want %q", got, PhaseUnprepared)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/jetstack/cni-migration/pkg/status"
+)
+
+// RunBatches will run fn against the given nodes in batches, where each batch
+// contains at most the configured max unavailable number of nodes, processed
+// concurrently. Nodes are ordered into groups using OrderNodes, and a batch
+// never contains nodes from more than one group. Knet-stress connectivity is
+// checked before the first batch and after each batch, so fn need not check
+// it. If any node in a batch fails, no further batches are started. Each call
+// of fn is bounded by the node operation timeout.
+//
+// If a Pause is set in the context, ErrPaused is returned once the configured
+// number of nodes have been processed, and in interactive mode the operator is
+// prompted after every batch. The operator is never prompted during a dry run.
+func (f *Factory) RunBatches(dryrun bool, nodes []corev1.Node, fn func(ctx context.Context, nodeName string) error) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	size, err := f.batchSize()
+	if err != nil {
+		return err
+	}
+
+	var batches [][]string
+	for _, group := range f.OrderNodes(nodes) {
+		for i := 0; i < len(group); i += size {
+			end := i + size
+			if end > len(group) {
+				end = len(group)
+			}
+
+			batches = append(batches, group[i:end])
+		}
+	}
+
+	if !dryrun {
+		if err := f.CheckConnectivity(); err != nil {
+			return err
+		}
+	}
+
+	pause := f.pauseFromContext()
+
+	for i := 0; i < len(batches); i++ {
+		batch := batches[i]
+
+		if pause != nil && pause.PauseAfter > 0 {
+			remaining := pause.PauseAfter - pause.processed
+			if remaining <= 0 {
+				f.log.Infof("paused after %d nodes, re-run to continue", pause.processed)
+				return ErrPaused
+			}
+
+			if len(batch) > remaining {
+				batch = batch[:remaining]
+			}
+		}
+
+		summaries, connectivity, err := f.runBatch(dryrun, batch, fn)
+		if err == nil {
+			err = connectivity
+		}
+
+		if pause == nil {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		pause.processed += len(batch)
+
+		// Nothing is changed during a dry run, so there is nothing for the
+		// operator to decide, and stdin may not be a terminal.
+		if !pause.Interactive || dryrun {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		var next []string
+		if i+1 < len(batches) {
+			next = batches[i+1]
+		}
+
+		result := "ok"
+		switch {
+		case connectivity != nil:
+			result = connectivity.Error()
+		case err != nil:
+			result = "not checked"
+		}
+
+		action, perr := pause.prompt(summaries, result, next)
+		if perr != nil {
+			return perr
+		}
+
+		switch action {
+		case ActionContinue:
+			if err != nil {
+				f.log.Warnf("continuing after failure: %s", err)
+			}
+
+		case ActionSkip:
+			if len(next) > 0 {
+				f.log.Infof("skipping nodes: %s", strings.Join(next, ", "))
+				i++
+			}
+
+		case ActionRetry:
+			pause.processed -= len(batch)
+			i--
+
+		case ActionAbort:
+			if err != nil {
+				return err
+			}
+			return errors.New("aborted by operator")
+		}
+	}
+
+	return nil
+}
+
+// runBatch will run fn against every node in the batch concurrently, then
+// check connectivity. Returns a summary of each node, the result of the
+// connectivity check, and an aggregate of all node errors.
+func (f *Factory) runBatch(dryrun bool, batch []string, fn func(ctx context.Context, nodeName string) error) ([]batchSummary, error, error) {
+	f.log.Infof("processing batch of %d nodes: %s", len(batch), strings.Join(batch, ", "))
+
+	var (
+		wg        sync.WaitGroup
+		lock      sync.Mutex
+		errs      []error
+		summaries = make([]batchSummary, len(batch))
+	)
+
+	for i, nodeName := range batch {
+		wg.Add(1)
+		go func(i int, nodeName string) {
+			defer wg.Done()
+
+			summary := batchSummary{node: nodeName}
+
+			pods, err := f.podsOnNode(nodeName)
+			if err != nil {
+				f.log.Warnf("failed to count pods on node %s: %s", nodeName, err)
+			}
+			summary.podsRolled = pods
+
+			err = WithTimeout(f.ctx, f.config.Timeouts.Node, "node operation", func(ctx context.Context) error {
+				return fn(ctx, nodeName)
+			})
+			if err != nil {
+				summary.err = err
+
+				lock.Lock()
+				errs = append(errs, fmt.Errorf("node %s: %s", nodeName, err))
+				lock.Unlock()
+			}
+
+			if node, err := f.client.CoreV1().Nodes().Get(f.ctx, nodeName, metav1.GetOptions{}); err == nil {
+				summary.phase = status.NodePhase(f.config.Labels, node)
+			}
+
+			summaries[i] = summary
+		}(i, nodeName)
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return summaries, nil, utilerrors.NewAggregate(errs)
+	}
+
+	if !dryrun {
+		return summaries, f.CheckConnectivity(), nil
+	}
+
+	return summaries, nil, nil
+}
+
+// batchSize returns the number of nodes which may be processed at once,
+// calculated from the configured max unavailable against the total number of
+// nodes in the cluster.
+func (f *Factory) batchSize() (int, error) {
+	maxUnavailable := f.config.Batch.MaxUnavailable
+	if len(maxUnavailable) == 0 {
+		return 1, nil
+	}
+
+	nodes, err := f.client.CoreV1().Nodes().List(f.ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	value := intstr.Parse(maxUnavailable)
+	size, err := intstr.GetValueFromIntOrPercent(&value, len(nodes.Items), false)
+	if err != nil {
+		return 0, fmt.Errorf("invalid maxUnavailable %q: %s", maxUnavailable, err)
+	}
+
+	if size < 1 {
+		size = 1
+	}
+
+	return size, nil
+}
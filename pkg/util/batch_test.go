@@ -0,0 +1,107 @@
+package util
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+func TestBatchSize(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		maxUnavailable string
+		nodes          int
+		want           int
+		expErr         bool
+	}{
+		{"unset", "", 10, 1, false},
+		{"absolute", "3", 10, 3, false},
+		{"absolute above nodes", "20", 10, 20, false},
+		{"zero", "0", 10, 1, false},
+		{"percentage", "50%", 10, 5, false},
+		{"percentage rounds down", "50%", 5, 2, false},
+		{"percentage below one node", "10%", 5, 1, false},
+		{"percentage of no nodes", "10%", 0, 1, false},
+		{"all nodes", "100%", 7, 7, false},
+		{"invalid", "ten", 10, 0, true},
+		{"invalid percentage", "ten%", 10, 0, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var objs []runtime.Object
+			for i := 0; i < test.nodes; i++ {
+				objs = append(objs, &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node-%d", i)},
+				})
+			}
+
+			cfg := &config.Config{
+				Client: fake.NewSimpleClientset(objs...),
+				Batch:  &config.Batch{MaxUnavailable: test.maxUnavailable},
+			}
+
+			got, err := New(context.Background(), nil, cfg).batchSize()
+			if test.expErr != (err != nil) {
+				t.Fatalf("expected error %t, got %v", test.expErr, err)
+			}
+
+			if got != test.want {
+				t.Errorf("got batch size %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+// failingReader fails the test if the operator is prompted.
+type failingReader struct {
+	t *testing.T
+}
+
+func (r failingReader) Read([]byte) (int, error) {
+	r.t.Error("operator was prompted during a dry run")
+	return 0, io.EOF
+}
+
+func TestRunBatchesDryRunNoPrompt(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	nodes := []corev1.Node{testNode("node-1", nil), testNode("node-2", nil)}
+
+	cfg := &config.Config{
+		Client:   fake.NewSimpleClientset(&nodes[0], &nodes[1]),
+		Labels:   new(config.Labels),
+		Batch:    new(config.Batch),
+		Ordering: new(config.Ordering),
+		Timeouts: new(config.Timeouts),
+	}
+
+	ctx := context.WithValue(context.Background(), ContextPauseKey, &Pause{
+		Interactive: true,
+		In:          bufio.NewReader(failingReader{t}),
+		Out:         ioutil.Discard,
+	})
+
+	var processed int
+	err := New(ctx, logrus.NewEntry(logger), cfg).RunBatches(true, nodes, func(context.Context, string) error {
+		processed++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if processed != len(nodes) {
+		t.Errorf("processed %d nodes, want %d", processed, len(nodes))
+	}
+}
@@ -0,0 +1,181 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Check is a connectivity check which is run alongside knet-stress after every
+// change to the cluster network.
+type Check interface {
+	// Name returns the name of the check.
+	Name() string
+
+	// Run will run the check once, returning an error if it failed.
+	Run() error
+}
+
+// CheckFunc builds a check using the factory.
+type CheckFunc func(f *Factory) Check
+
+var checks = map[string]CheckFunc{}
+
+// RegisterCheck will register a check under the given name, so that it can be
+// enabled in config.
+func RegisterCheck(name string, fn CheckFunc) {
+	checks[name] = fn
+}
+
+// CheckConnectivity will check knet-stress connectivity, then run every
+// enabled check until it succeeds, or the knet-stress timeout is reached,
+// followed by the configured health checks.
+//
+// The whole check is bounded by the connectivity timeout.
+func (f *Factory) CheckConnectivity() error {
+	return f.withTimeout(f.config.Timeouts.Connectivity, "connectivity check", func(f *Factory) error {
+		return f.checkConnectivity()
+	})
+}
+
+func (f *Factory) checkConnectivity() error {
+	if err := f.CheckKnetStress(); err != nil {
+		return err
+	}
+
+	for _, name := range f.config.Checks.Enabled {
+		fn, ok := checks[name]
+		if !ok {
+			return fmt.Errorf("unknown check %q", name)
+		}
+
+		if err := f.runCheck(fn(f)); err != nil {
+			return err
+		}
+	}
+
+	return f.RunHealthChecks()
+}
+
+func (f *Factory) runCheck(check Check) error {
+	f.log.Infof("running %s check...", check.Name())
+
+	timeout := time.NewTimer(f.config.KnetStress.Timeout)
+	defer timeout.Stop()
+
+	ticker := time.NewTicker(f.config.KnetStress.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := check.Run()
+		if err == nil {
+			f.log.Infof("%s check ok", check.Name())
+			return nil
+		}
+
+		f.log.Errorf("%s check failed: %s", check.Name(), err)
+
+		select {
+		case <-f.ctx.Done():
+			return fmt.Errorf("%s check failed: %s", check.Name(), f.ctx.Err())
+		case <-timeout.C:
+			return fmt.Errorf("%s check failed after %s: %s", check.Name(), f.config.KnetStress.Timeout, err)
+		case <-ticker.C:
+			continue
+		}
+	}
+}
+
+// knetStressPodPerNode returns a single running knet-stress pod on every node,
+// so that checks can be run from the pod network of each node.
+func (f *Factory) knetStressPodPerNode() ([]corev1.Pod, error) {
+	return f.podPerNode(f.config.KnetStress.Namespace, f.config.KnetStress.Selector)
+}
+
+// podPerNode returns a single running pod matching the selector on every node,
+// sorted by node name.
+func (f *Factory) podPerNode(namespace, selector string) ([]corev1.Pod, error) {
+	pods, err := f.client.CoreV1().Pods(namespace).List(f.ctx, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	perNode := make(map[string]corev1.Pod)
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if _, ok := perNode[pod.Spec.NodeName]; !ok {
+			perNode[pod.Spec.NodeName] = pod
+		}
+	}
+
+	var result []corev1.Pod
+	for _, pod := range perNode {
+		result = append(result, pod)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Spec.NodeName < result[j].Spec.NodeName
+	})
+
+	return result, nil
+}
+
+// execOnEveryNode will run the command in the probe container of a knet-stress
+// pod on every node, returning an aggregate of all failures.
+func (f *Factory) execOnEveryNode(command []string) error {
+	pods, err := f.knetStressPodPerNode()
+	if err != nil {
+		return err
+	}
+
+	return f.execOnPods(pods, func(*corev1.Pod) []string {
+		return command
+	})
+}
+
+// execOnPods will run the command built for each pod in its probe container,
+// running at most the knet-stress concurrency of commands at once. Returns an
+// aggregate of all failures.
+func (f *Factory) execOnPods(pods []corev1.Pod, command func(pod *corev1.Pod) []string) error {
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, f.config.KnetStress.Concurrency)
+	)
+
+	for i := range pods {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(pod *corev1.Pod) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := f.execPod(f.ctx, pod, f.config.KnetStress.Container, command(pod)); err != nil {
+				lock.Lock()
+				errs = append(errs, fmt.Errorf("node %s: %s", pod.Spec.NodeName, err))
+				lock.Unlock()
+			}
+		}(&pods[i])
+	}
+
+	wg.Wait()
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+
+	return utilerrors.NewAggregate(errs)
+}
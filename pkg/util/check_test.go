@@ -0,0 +1,26 @@
+package util
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+// TestRegisteredChecks ensures the checks accepted by config validation are
+// exactly the checks registered to run.
+func TestRegisteredChecks(t *testing.T) {
+	var registered []string
+	for name := range checks {
+		registered = append(registered, name)
+	}
+	sort.Strings(registered)
+
+	want := append([]string(nil), config.CheckNames...)
+	sort.Strings(want)
+
+	if !reflect.DeepEqual(registered, want) {
+		t.Errorf("got registered checks %v, want %v", registered, want)
+	}
+}
@@ -0,0 +1,64 @@
+package util
+
+import (
+	"encoding/json"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// CheckpointAnnotation holds the last completed sub-step of the step
+	// currently processing the node, as "<step>/<sub-step>".
+	CheckpointAnnotation = "cni-migration.jetstack.io/checkpoint"
+)
+
+// Checkpoint returns the last completed sub-step of step on the node, or
+// empty if none.
+func Checkpoint(node *corev1.Node, step string) string {
+	v, ok := node.Annotations[CheckpointAnnotation]
+	if !ok || !strings.HasPrefix(v, step+"/") {
+		return ""
+	}
+
+	return strings.TrimPrefix(v, step+"/")
+}
+
+// SetCheckpoint will record subStep of step as completed on the node. Nothing
+// is recorded during a dry run.
+func (f *Factory) SetCheckpoint(dryrun bool, nodeName, step, subStep string) error {
+	f.log.Debugf("checkpointing node %s at %s/%s", nodeName, step, subStep)
+	if dryrun {
+		return nil
+	}
+
+	return f.patchCheckpoint(nodeName, step+"/"+subStep)
+}
+
+// ClearCheckpoint will remove the checkpoint from the node. Nothing is
+// removed during a dry run.
+func (f *Factory) ClearCheckpoint(dryrun bool, nodeName string) error {
+	if dryrun {
+		return nil
+	}
+
+	return f.patchCheckpoint(nodeName, nil)
+}
+
+func (f *Factory) patchCheckpoint(nodeName string, value interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				CheckpointAnnotation: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = f.client.CoreV1().Nodes().Patch(f.ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
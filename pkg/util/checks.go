@@ -0,0 +1,127 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func init() {
+	RegisterCheck("clusterIP", func(f *Factory) Check { return &clusterIPCheck{f} })
+	RegisterCheck("dns", func(f *Factory) Check { return &dnsCheck{f} })
+	RegisterCheck("nodePort", func(f *Factory) Check { return &nodePortCheck{f} })
+	RegisterCheck("egress", func(f *Factory) Check { return &egressCheck{f} })
+}
+
+// clusterIPCheck runs the knet-stress command from a pod on every node
+// against the cluster IP of a Service, testing kube-proxy.
+type clusterIPCheck struct {
+	*Factory
+}
+
+func (c *clusterIPCheck) Name() string {
+	return "clusterIP"
+}
+
+func (c *clusterIPCheck) Run() error {
+	cfg := c.config.Checks.ClusterIP
+
+	svc, err := c.client.CoreV1().Services(cfg.Namespace).Get(c.ctx, cfg.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if len(svc.Spec.Ports) == 0 || len(svc.Spec.ClusterIP) == 0 || svc.Spec.ClusterIP == corev1.ClusterIPNone {
+		return fmt.Errorf("service %s/%s has no cluster IP and port", cfg.Namespace, cfg.Name)
+	}
+
+	address := net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(int(svc.Spec.Ports[0].Port)))
+
+	return c.execOnEveryNode(replaceAll(c.config.KnetStress.Command, "{address}", address))
+}
+
+// dnsCheck looks up names from a pod on every node, testing cluster DNS.
+type dnsCheck struct {
+	*Factory
+}
+
+func (d *dnsCheck) Name() string {
+	return "dns"
+}
+
+func (d *dnsCheck) Run() error {
+	var errs []error
+	for _, name := range d.config.Checks.DNS.Names {
+		if err := d.execOnEveryNode(replaceAll(d.config.Checks.DNS.Command, "{name}", name)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", name, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// nodePortCheck requests the node port of a Service on every node from a pod
+// on the node, testing NodePort traffic to the host network of the node.
+type nodePortCheck struct {
+	*Factory
+}
+
+func (n *nodePortCheck) Name() string {
+	return "nodePort"
+}
+
+func (n *nodePortCheck) Run() error {
+	cfg := n.config.Checks.NodePort
+
+	svc, err := n.client.CoreV1().Services(cfg.Namespace).Get(n.ctx, cfg.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if len(svc.Spec.Ports) == 0 || svc.Spec.Ports[0].NodePort == 0 {
+		return fmt.Errorf("service %s/%s has no node port", cfg.Namespace, cfg.Name)
+	}
+	nodePort := strconv.Itoa(int(svc.Spec.Ports[0].NodePort))
+
+	pods, err := n.knetStressPodPerNode()
+	if err != nil {
+		return err
+	}
+
+	return n.execOnPods(pods, func(pod *corev1.Pod) []string {
+		url := "http://" + net.JoinHostPort(pod.Status.HostIP, nodePort) + cfg.Path
+		return replaceAll(cfg.Command, "{url}", url)
+	})
+}
+
+// egressCheck requests a configured URL from a pod on every node.
+type egressCheck struct {
+	*Factory
+}
+
+func (e *egressCheck) Name() string {
+	return "egress"
+}
+
+func (e *egressCheck) Run() error {
+	cfg := e.config.Checks.Egress
+	if len(cfg.URL) == 0 {
+		return fmt.Errorf("egress check enabled but no url configured")
+	}
+
+	return e.execOnEveryNode(replaceAll(cfg.Command, "{url}", cfg.URL))
+}
+
+// replaceAll returns a copy of args with old replaced with new in every arg.
+func replaceAll(args []string, old, new string) []string {
+	var result []string
+	for _, arg := range args {
+		result = append(result, strings.ReplaceAll(arg, old, new))
+	}
+	return result
+}
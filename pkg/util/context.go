@@ -6,11 +6,35 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 )
 
-func NodesFromContext(client *kubernetes.Clientset, ctx context.Context, key string) ([]corev1.Node, bool, error) {
-	v := ctx.Value(key)
+// NodesFromContext returns the nodes selected for a step from the context,
+// either by a list of node names stored at namesKey, or by a label selector
+// stored at selectorKey. Returns false if neither are set.
+func NodesFromContext(client kubernetes.Interface, ctx context.Context, namesKey, selectorKey string) ([]corev1.Node, bool, error) {
+	if v := ctx.Value(selectorKey); v != nil {
+		selector, ok := v.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("failed to get node selector from context: %#+v", v)
+		}
+
+		if _, err := labels.Parse(selector); err != nil {
+			return nil, false, fmt.Errorf("invalid node selector %q: %s", selector, err)
+		}
+
+		nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+			LabelSelector: selector,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list nodes with selector %q: %s", selector, err)
+		}
+
+		return nodes.Items, true, nil
+	}
+
+	v := ctx.Value(namesKey)
 	if v == nil {
 		return nil, false, nil
 	}
@@ -0,0 +1,200 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+const (
+	// mirrorPodAnnotation is set by the Kubelet on pods created from static
+	// manifests. These pods cannot be evicted through the API server.
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+// DrainNode will cordon the node and evict all pods running on it using the
+// Eviction API, respecting PodDisruptionBudgets. DaemonSet and mirror pods are
+// ignored. Pods not managed by a controller will cause the drain to fail.
+func (f *Factory) DrainNode(nodeName string) error {
+	// Cordon before listing, so no pods are scheduled which would be missed.
+	if err := f.CordonNode(nodeName); err != nil {
+		return err
+	}
+
+	pods, err := f.podsToEvict(nodeName)
+	if err != nil {
+		return err
+	}
+
+	ctx := f.ctx
+	if f.config.Drain.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(f.ctx, f.config.Drain.Timeout)
+		defer cancel()
+	}
+
+	for _, pod := range pods {
+		f.log.Debugf("evicting pod %s/%s on node %s", pod.Namespace, pod.Name, nodeName)
+
+		if err := f.evictPod(ctx, pod); err != nil {
+			return fmt.Errorf("failed to drain node %s: %s", nodeName, err)
+		}
+	}
+
+	if err := f.waitPodsDeleted(ctx, pods); err != nil {
+		return fmt.Errorf("failed to drain node %s: %s", nodeName, err)
+	}
+
+	return nil
+}
+
+// CordonNode will mark the node as unschedulable.
+func (f *Factory) CordonNode(nodeName string) error {
+	return f.setUnschedulable(nodeName, true)
+}
+
+// UncordonNode will mark the node as schedulable.
+func (f *Factory) UncordonNode(nodeName string) error {
+	return f.setUnschedulable(nodeName, false)
+}
+
+func (f *Factory) setUnschedulable(nodeName string, unschedulable bool) error {
+	node, err := f.client.CoreV1().Nodes().Get(f.ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+
+	node.Spec.Unschedulable = unschedulable
+
+	_, err = f.client.CoreV1().Nodes().Update(f.ctx, node, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// podsToEvict returns the pods on the node which need to be evicted.
+func (f *Factory) podsToEvict(nodeName string) ([]corev1.Pod, error) {
+	pods, err := f.client.CoreV1().Pods(metav1.NamespaceAll).List(f.ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		toEvict   []corev1.Pod
+		unmanaged []string
+	)
+
+	for _, pod := range pods.Items {
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+
+		controller := metav1.GetControllerOf(&pod)
+		if controller == nil {
+			// Completed pods are safe to remove
+			if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+				unmanaged = append(unmanaged, pod.Namespace+"/"+pod.Name)
+				continue
+			}
+		} else if controller.Kind == "DaemonSet" {
+			continue
+		}
+
+		toEvict = append(toEvict, pod)
+	}
+
+	if len(unmanaged) > 0 {
+		return nil, fmt.Errorf("cannot drain node %s, pods not managed by a controller: %s",
+			nodeName, strings.Join(unmanaged, ", "))
+	}
+
+	return toEvict, nil
+}
+
+// evictPod will evict the pod, retrying whilst the eviction is blocked by a
+// PodDisruptionBudget.
+func (f *Factory) evictPod(ctx context.Context, pod corev1.Pod) error {
+	eviction := &policyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: f.config.Drain.GracePeriodSeconds,
+		},
+	}
+
+	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+
+	for {
+		err := f.client.PolicyV1beta1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		if err == nil || apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		if !apierrors.IsTooManyRequests(err) {
+			return fmt.Errorf("failed to evict pod %s/%s: %s", pod.Namespace, pod.Name, err)
+		}
+
+		f.log.Debugf("eviction of pod %s/%s blocked by disruption budget, retrying: %s",
+			pod.Namespace, pod.Name, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out evicting pod %s/%s: %s", pod.Namespace, pod.Name, ctx.Err())
+		case <-ticker.C:
+			continue
+		}
+	}
+}
+
+// waitPodsDeleted will wait for all given pods to be removed. Pods which have
+// been re-created with the same name are considered deleted.
+func (f *Factory) waitPodsDeleted(ctx context.Context, pods []corev1.Pod) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		var remaining []corev1.Pod
+		for _, pod := range pods {
+			p, err := f.client.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) || (err == nil && p.UID != pod.UID) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			remaining = append(remaining, pod)
+		}
+
+		if len(remaining) == 0 {
+			return nil
+		}
+
+		pods = remaining
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %d pods to be deleted: %s", len(pods), ctx.Err())
+		case <-ticker.C:
+			continue
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+// TestDrainNodeCordonsBeforeListingPods ensures no pod can be scheduled to
+// the node after the pods to evict have been listed.
+func TestDrainNodeCordonsBeforeListingPods(t *testing.T) {
+	controller := true
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "app",
+			UID:       "app-uid",
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app", Controller: &controller},
+			},
+		},
+		Spec: corev1.PodSpec{NodeName: "node-1"},
+	}
+
+	client := fake.NewSimpleClientset(node, pod)
+
+	var listed bool
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		listed = true
+
+		obj, err := client.Tracker().Get(corev1.SchemeGroupVersion.WithResource("nodes"), "", "node-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !obj.(*corev1.Node).Spec.Unschedulable {
+			t.Error("pods listed before the node was cordoned")
+		}
+
+		return false, nil, nil
+	})
+
+	// Evicting a pod deletes it immediately.
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		return true, nil, client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	})
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	cfg := &config.Config{
+		Client: client,
+		Drain:  new(config.Drain),
+	}
+
+	f := New(context.Background(), logrus.NewEntry(logger), cfg)
+	if err := f.DrainNode("node-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !listed {
+		t.Error("expected pods on the node to be listed")
+	}
+
+	if _, err := client.CoreV1().Pods("default").Get(context.Background(), "app", metav1.GetOptions{}); err == nil {
+		t.Error("expected pod to be evicted")
+	}
+}
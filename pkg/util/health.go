@@ -0,0 +1,218 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+// RunHealthChecks will run every configured health check, retrying each
+// failed check up to its configured retries.
+func (f *Factory) RunHealthChecks() error {
+	for _, check := range f.config.HealthChecks {
+		if err := f.runHealthCheck(check); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *Factory) runHealthCheck(check *config.HealthCheck) error {
+	f.log.Infof("running health check %s...", check.Name)
+
+	var err error
+	for attempt := 0; attempt <= check.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-f.ctx.Done():
+				return fmt.Errorf("health check %s failed: %s", check.Name, f.ctx.Err())
+			case <-time.After(f.config.KnetStress.Interval):
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(f.ctx, check.Timeout)
+		switch {
+		case check.HTTP != nil:
+			err = f.httpHealthCheck(ctx, check.HTTP)
+		case check.Exec != nil:
+			err = f.execHealthCheck(ctx, check.Exec)
+		case check.Prometheus != nil:
+			err = f.prometheusHealthCheck(ctx, check.Prometheus)
+		}
+		cancel()
+
+		if err == nil {
+			f.log.Infof("health check %s ok", check.Name)
+			return nil
+		}
+
+		f.log.Errorf("health check %s failed (attempt %d/%d): %s", check.Name, attempt+1, check.Retries+1, err)
+	}
+
+	return fmt.Errorf("health check %s failed after %d attempts: %s", check.Name, check.Retries+1, err)
+}
+
+func (f *Factory) httpHealthCheck(ctx context.Context, check *config.HTTPHealthCheck) error {
+	_, err := f.client.CoreV1().Services(check.Namespace).
+		ProxyGet(check.Scheme, check.Service, check.Port, check.Path, nil).
+		DoRaw(ctx)
+	return err
+}
+
+func (f *Factory) execHealthCheck(ctx context.Context, check *config.ExecHealthCheck) error {
+	pods, err := f.client.CoreV1().Pods(check.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: check.Selector,
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	var ran bool
+	for i, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		ran = true
+		if err := f.execPod(ctx, &pods.Items[i], check.Container, check.Command); err != nil {
+			errs = append(errs, fmt.Errorf("pod %s: %s", pod.Name, err))
+		}
+	}
+
+	if !ran {
+		return fmt.Errorf("no running pods in namespace %s matching %q", check.Namespace, check.Selector)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// prometheusResponse is the response of the Prometheus instant query API.
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+func (f *Factory) prometheusHealthCheck(ctx context.Context, check *config.PrometheusHealthCheck) error {
+	var (
+		body []byte
+		err  error
+	)
+
+	if len(check.URL) > 0 {
+		body, err = prometheusQuery(ctx, check.URL, check.Query)
+	} else {
+		body, err = f.client.CoreV1().Services(check.Namespace).
+			ProxyGet("", check.Service, check.Port, "/api/v1/query", map[string]string{"query": check.Query}).
+			DoRaw(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	var resp prometheusResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("failed to decode prometheus response: %s", err)
+	}
+
+	if resp.Status != "success" {
+		return fmt.Errorf("prometheus query %q failed: %s", check.Query, resp.Error)
+	}
+
+	if resp.Data.ResultType != "vector" {
+		return fmt.Errorf("prometheus query %q returned %s, expected vector", check.Query, resp.Data.ResultType)
+	}
+
+	if len(resp.Data.Result) == 0 {
+		return fmt.Errorf("prometheus query %q returned no samples", check.Query)
+	}
+
+	var errs []error
+	for _, sample := range resp.Data.Result {
+		if len(sample.Value) != 2 {
+			return fmt.Errorf("unexpected prometheus sample %v", sample.Value)
+		}
+
+		s, ok := sample.Value[1].(string)
+		if !ok {
+			return fmt.Errorf("unexpected prometheus sample value %v", sample.Value[1])
+		}
+
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse prometheus sample value %q: %s", s, err)
+		}
+
+		if !compare(value, check.Operator, check.Threshold) {
+			errs = append(errs, fmt.Errorf("%v: %v %s %v not satisfied",
+				sample.Metric, value, check.Operator, check.Threshold))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func prometheusQuery(ctx context.Context, endpoint, query string) ([]byte, error) {
+	u := strings.TrimSuffix(endpoint, "/") + "/api/v1/query?" + url.Values{"query": {query}}.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unexpected status %s from %s: %s", resp.Status, endpoint, body)
+	}
+
+	return body, nil
+}
+
+func compare(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	default:
+		return false
+	}
+}
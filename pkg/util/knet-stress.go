@@ -1,49 +1,455 @@
 package util
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+
+	"github.com/jetstack/cni-migration/pkg/status"
 )
 
+// ProbeEndpoint is a knet-stress pod which is the source or destination of a
+// probe, annotated with the CNIs attached to the pod and the migration phase of
+// its node.
+type ProbeEndpoint struct {
+	Pod       string       `json:"pod" yaml:"pod"`
+	DaemonSet string       `json:"daemonset" yaml:"daemonset"`
+	Node      string       `json:"node" yaml:"node"`
+	IP        string       `json:"ip" yaml:"ip"`
+	CNI       string       `json:"cni" yaml:"cni"`
+	Phase     status.Phase `json:"phase" yaml:"phase"`
+}
+
+// ProbeResult is the result of a single probe from a source to a destination
+// knet-stress pod.
+type ProbeResult struct {
+	Source      ProbeEndpoint `json:"source" yaml:"source"`
+	Destination ProbeEndpoint `json:"destination" yaml:"destination"`
+	OK          bool          `json:"ok" yaml:"ok"`
+	Latency     time.Duration `json:"latency" yaml:"latency"`
+	Error       string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ProbeMatrix holds the results of probing a sample of knet-stress pods from
+// every knet-stress pod.
+type ProbeMatrix struct {
+	Results []ProbeResult `json:"results" yaml:"results"`
+}
+
+// Failed returns all results which did not succeed.
+func (p *ProbeMatrix) Failed() []ProbeResult {
+	var failed []ProbeResult
+	for _, r := range p.Results {
+		if !r.OK {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// CheckKnetStress will wait for the knet-stress DaemonSets to become ready,
+// then probe connectivity between knet-stress pods until every probe succeeds,
+// or the configured timeout is reached. The timeout also bounds every probe,
+// so a hung exec cannot outlive it. The same sample of destinations is probed
+// until it succeeds, and a new sample is taken on every check.
 func (f *Factory) CheckKnetStress() error {
 	f.log.Info("checking knet-stress connectivity...")
 
-	if err := f.WaitDaemonSetReady("knet-stress", "knet-stress"); err != nil {
+	cfg := f.config.KnetStress
+
+	if err := f.WaitKnetStressReady(); err != nil {
 		return err
 	}
 
-	ticker := time.NewTicker(time.Second * 5)
+	ctx, cancel := context.WithTimeout(f.ctx, cfg.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	offset := rand.New(rand.NewSource(time.Now().UnixNano())).Int()
 
 	for {
-		pods, err := f.client.CoreV1().Pods("knet-stress").List(f.ctx, metav1.ListOptions{
-			LabelSelector: "app=knet-stress",
-		})
+		matrix, err := f.ProbeKnetStress(ctx, offset)
 		if err != nil {
+			if f.ctx.Err() == nil && ctx.Err() != nil {
+				return fmt.Errorf("knet-stress connectivity failed after %s: %s", cfg.Timeout, err)
+			}
 			return err
 		}
 
-		ready := true
-		for _, pod := range pods.Items {
-			args := []string{"kubectl", "exec", "--namespace", "knet-stress", pod.Name, "--", "/knet-stress", "status"}
-			if err := f.RunCommand(os.Stdout, args...); err != nil {
-				f.log.Error(err.Error())
-				ready = false
-				break
-			}
+		if len(matrix.Results) == 0 {
+			return errors.New("knet-stress connectivity failed: no probes were run")
 		}
 
-		if ready {
+		failed := matrix.Failed()
+		if len(failed) == 0 {
+			f.log.Infof("knet-stress connectivity ok (%d probes)", len(matrix.Results))
 			return nil
 		}
 
+		f.log.Errorf("knet-stress connectivity failed for %d/%d probes", len(failed), len(matrix.Results))
+		for _, r := range failed {
+			f.log.Debugf("%s (%s) -> %s (%s): %s",
+				r.Source.Pod, r.Source.Node, r.Destination.Pod, r.Destination.Node, r.Error)
+		}
+
+		report := matrix.Report()
+		for _, p := range report.FailingPhasePairs() {
+			f.log.Errorf("phase %s -> %s: %d/%d probes failing", p.Source, p.Destination, p.Failed, p.Total)
+		}
+
 		select {
-		case <-f.ctx.Done():
-			return fmt.Errorf("knet-stress connectivity failed: %s", f.ctx.Err())
+		case <-ctx.Done():
+			if f.ctx.Err() != nil {
+				return fmt.Errorf("knet-stress connectivity failed: %s", f.ctx.Err())
+			}
+
+			for _, p := range report.FailingNodePairs() {
+				f.log.Errorf("node %s -> %s: %d/%d probes failing", p.Source, p.Destination, p.Failed, p.Total)
+			}
+
+			if len(cfg.ReportPath) > 0 {
+				if err := report.WriteFile(cfg.ReportPath); err != nil {
+					f.log.Errorf("failed to write connectivity report: %s", err)
+				} else {
+					f.log.Infof("connectivity report written to %s", cfg.ReportPath)
+				}
+			}
+
+			return fmt.Errorf("knet-stress connectivity failed after %s: %d/%d probes failing, first: %s -> %s: %s",
+				cfg.Timeout, len(failed), len(matrix.Results), failed[0].Source.Pod, failed[0].Destination.Pod, failed[0].Error)
 		case <-ticker.C:
 			continue
 		}
 	}
 }
+
+// WaitKnetStressReady will wait for every knet-stress DaemonSet to become
+// ready.
+func (f *Factory) WaitKnetStressReady() error {
+	cfg := f.config.KnetStress
+
+	dss, err := f.client.AppsV1().DaemonSets(cfg.Namespace).List(f.ctx, metav1.ListOptions{
+		LabelSelector: cfg.Selector,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(dss.Items) == 0 {
+		return fmt.Errorf("no knet-stress DaemonSets found in namespace %q matching %q", cfg.Namespace, cfg.Selector)
+	}
+
+	for _, ds := range dss.Items {
+		if err := f.WaitDaemonSetReady(ds.Namespace, ds.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ProbeKnetStress will probe the configured number of destinations from every
+// knet-stress pod once, running at most the configured concurrency of probes
+// at once. Probes still running when the context is done fail. The
+// destinations of each pod are chosen by offset, see probeDestinations.
+func (f *Factory) ProbeKnetStress(ctx context.Context, offset int) (*ProbeMatrix, error) {
+	cfg := f.config.KnetStress
+
+	pods, err := f.client.CoreV1().Pods(cfg.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: cfg.Selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodePhases, err := f.nodePhases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && len(pod.Status.PodIP) > 0 {
+			endpoints = append(endpoints, pod)
+		}
+	}
+
+	if len(endpoints) < 2 {
+		return nil, fmt.Errorf("found %d running knet-stress pods in namespace %q matching %q, at least 2 are required",
+			len(endpoints), cfg.Namespace, cfg.Selector)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		matrix = new(ProbeMatrix)
+		sem    = make(chan struct{}, cfg.Concurrency)
+	)
+
+	for i := range endpoints {
+		for _, j := range probeDestinations(i, len(endpoints), *cfg.Destinations, offset) {
+			wg.Add(1)
+			sem <- struct{}{}
+
+			go func(src, dst *corev1.Pod) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				result := f.probe(ctx, src, dst)
+				result.Source = f.probeEndpoint(src, nodePhases)
+				result.Destination = f.probeEndpoint(dst, nodePhases)
+
+				lock.Lock()
+				matrix.Results = append(matrix.Results, result)
+				lock.Unlock()
+			}(&endpoints[i], &endpoints[j])
+		}
+	}
+
+	wg.Wait()
+
+	sort.Slice(matrix.Results, func(i, j int) bool {
+		a, b := matrix.Results[i], matrix.Results[j]
+		if a.Source.Pod != b.Source.Pod {
+			return a.Source.Pod < b.Source.Pod
+		}
+		return a.Destination.Pod < b.Destination.Pod
+	})
+
+	return matrix, nil
+}
+
+// probeDestinations returns the indexes of the endpoints probed from the
+// endpoint at index i of n endpoints. Up to count other endpoints are spread
+// evenly around the endpoints, starting from offset, so that every pair is
+// eventually probed as the offset changes. Every other endpoint is returned if
+// count is zero.
+func probeDestinations(i, n, count, offset int) []int {
+	dsts := sample(n-1, count, offset)
+	for k := range dsts {
+		dsts[k] = (i + 1 + dsts[k]) % n
+	}
+
+	return dsts
+}
+
+// sample returns count distinct indexes of n, spread evenly starting from
+// offset. Every index is returned if count is zero or at least n.
+func sample(n, count, offset int) []int {
+	if n <= 0 {
+		return nil
+	}
+
+	if count == 0 || count > n {
+		count = n
+	}
+
+	offset %= n
+	if offset < 0 {
+		offset += n
+	}
+
+	stride := n / count
+
+	indexes := make([]int, count)
+	for k := range indexes {
+		indexes[k] = (offset + k*stride) % n
+	}
+
+	return indexes
+}
+
+// probe will exec the probe command in the probe container of the source pod
+// against the destination pod, until the context is done.
+func (f *Factory) probe(ctx context.Context, src, dst *corev1.Pod) ProbeResult {
+	var result ProbeResult
+
+	address := net.JoinHostPort(dst.Status.PodIP, strconv.Itoa(int(f.config.KnetStress.Port)))
+
+	start := time.Now()
+	err := f.execPod(ctx, src, f.config.KnetStress.Container, replaceAll(f.config.KnetStress.Command, "{address}", address))
+	result.Latency = time.Since(start)
+
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.OK = true
+	}
+
+	return result
+}
+
+// nodePhases returns the migration phase of every node, by node name.
+func (f *Factory) nodePhases(ctx context.Context) (map[string]status.Phase, error) {
+	nodes, err := f.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	phases := make(map[string]status.Phase)
+	for _, node := range nodes.Items {
+		phases[node.Name] = status.NodePhase(f.config.Labels, &node)
+	}
+
+	return phases, nil
+}
+
+// probeEndpoint returns the endpoint of a knet-stress pod. The CNIs attached
+// to the pod are inferred from the migration phase of its node.
+func (f *Factory) probeEndpoint(pod *corev1.Pod, nodePhases map[string]status.Phase) ProbeEndpoint {
+	endpoint := ProbeEndpoint{
+		Pod:   pod.Name,
+		Node:  pod.Spec.NodeName,
+		IP:    pod.Status.PodIP,
+		Phase: nodePhases[pod.Spec.NodeName],
+	}
+
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			endpoint.DaemonSet = ref.Name
+		}
+	}
+
+	switch endpoint.Phase {
+	case status.PhaseUnprepared, status.PhaseCanalPrimary:
+		endpoint.CNI = f.config.CNI.Source.Name
+	case status.PhaseRolled, status.PhaseCiliumPrimary:
+		endpoint.CNI = "both"
+	default:
+		endpoint.CNI = f.config.CNI.Target.Name
+	}
+
+	return endpoint
+}
+
+// ExecPod will run the command in the first container of the pod, using the
+// pods/exec subresource. Returns an error containing stderr if the command
+// fails.
+func (f *Factory) ExecPod(pod *corev1.Pod, command []string) error {
+	return f.execPod(f.ctx, pod, "", command)
+}
+
+// execPod will run the command in the container of the pod, or the first
+// container if empty, until the context is done.
+func (f *Factory) execPod(ctx context.Context, pod *corev1.Pod, container string, command []string) error {
+	_, err := f.execPodOutput(ctx, pod, container, command)
+	return err
+}
+
+// execPodOutput will run the command in the container of the pod, returning
+// its stdout. If container is empty, the first container is used.
+func (f *Factory) execPodOutput(ctx context.Context, pod *corev1.Pod, container string, command []string) (string, error) {
+	if len(container) == 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+
+	req := f.client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	transport, upgrader, err := spdy.RoundTripperFor(f.config.RESTConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to exec in pod %s/%s: %s", pod.Namespace, pod.Name, err)
+	}
+
+	conn := &connUpgrader{Upgrader: upgrader}
+	defer conn.Close()
+
+	exec, err := remotecommand.NewSPDYExecutorForTransports(transport, conn, "POST", req.URL())
+	if err != nil {
+		return "", fmt.Errorf("failed to exec in pod %s/%s: %s", pod.Namespace, pod.Name, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- exec.Stream(remotecommand.StreamOptions{
+			Stdout: &stdout,
+			Stderr: &stderr,
+		})
+	}()
+
+	select {
+	case <-ctx.Done():
+		// Closing the connection ends the stream, so that its goroutine
+		// returns.
+		return "", ctx.Err()
+	case err := <-errCh:
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); len(msg) > 0 {
+				return "", fmt.Errorf("%s: %s", err, msg)
+			}
+			return "", err
+		}
+	}
+
+	return stdout.String(), nil
+}
+
+// connUpgrader is a spdy.Upgrader which records the connection of the stream,
+// so that the stream can be ended by closing it.
+type connUpgrader struct {
+	spdy.Upgrader
+
+	lock   sync.Mutex
+	conn   httpstream.Connection
+	closed bool
+}
+
+// NewConnection will upgrade the response to a connection, closing it
+// immediately if the upgrader has already been closed.
+func (c *connUpgrader) NewConnection(resp *http.Response) (httpstream.Connection, error) {
+	conn, err := c.Upgrader.NewConnection(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.conn = conn
+	if c.closed {
+		conn.Close()
+	}
+
+	return conn, nil
+}
+
+// Close will close the connection, if any, and any connection made after.
+func (c *connUpgrader) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.closed = true
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
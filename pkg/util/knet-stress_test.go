@@ -0,0 +1,162 @@
+package util
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+func TestProbeDestinations(t *testing.T) {
+	for _, test := range []struct {
+		name                string
+		i, n, count, offset int
+		want                []int
+	}{
+		{"single endpoint", 0, 1, 3, 0, nil},
+		{"all others", 1, 4, 0, 0, []int{2, 3, 0}},
+		{"count above others", 1, 4, 10, 5, []int{0, 2, 3}},
+		{"sample", 0, 10, 3, 0, []int{1, 4, 7}},
+		{"sample with offset", 0, 10, 3, 2, []int{3, 6, 9}},
+		{"sample wraps around", 8, 10, 3, 0, []int{9, 2, 5}},
+		{"negative offset", 0, 10, 3, -1, []int{9, 3, 6}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := probeDestinations(test.i, test.n, test.count, test.offset)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// TestProbeDestinationsCoverage ensures every destination is distinct and
+// never the source, and that every pair is probed across all offsets.
+func TestProbeDestinationsCoverage(t *testing.T) {
+	const n, count = 7, 2
+
+	probed := make(map[[2]int]bool)
+	for offset := 0; offset < n-1; offset++ {
+		for i := 0; i < n; i++ {
+			dsts := probeDestinations(i, n, count, offset)
+			if len(dsts) != count {
+				t.Fatalf("offset %d: got %d destinations from %d, want %d", offset, len(dsts), i, count)
+			}
+
+			seen := make(map[int]bool)
+			for _, j := range dsts {
+				if j == i {
+					t.Errorf("offset %d: %d probes itself", offset, i)
+				}
+				if seen[j] {
+					t.Errorf("offset %d: %d probes %d twice", offset, i, j)
+				}
+				seen[j] = true
+				probed[[2]int{i, j}] = true
+			}
+		}
+	}
+
+	if want := n * (n - 1); len(probed) != want {
+		t.Errorf("got %d pairs probed, want %d", len(probed), want)
+	}
+}
+
+func TestSample(t *testing.T) {
+	for _, test := range []struct {
+		name             string
+		n, count, offset int
+		want             []int
+	}{
+		{"empty", 0, 3, 0, nil},
+		{"all", 3, 0, 1, []int{1, 2, 0}},
+		{"count above n", 2, 5, 0, []int{0, 1}},
+		{"spread", 9, 3, 1, []int{1, 4, 7}},
+		{"uneven spread", 10, 3, 0, []int{0, 3, 6}},
+		{"offset wraps around", 9, 3, 17, []int{8, 2, 5}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sample(test.n, test.count, test.offset); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestKnetStressRequiresPods(t *testing.T) {
+	labels := map[string]string{"app": "knet-stress"}
+
+	knetStressPod := func(name string, phase corev1.PodPhase, ip string) runtime.Object {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "knet-stress", Labels: labels},
+			Status:     corev1.PodStatus{Phase: phase, PodIP: ip},
+		}
+	}
+
+	for _, test := range []struct {
+		name string
+		objs []runtime.Object
+		wait bool
+		err  string
+	}{
+		{
+			name: "no DaemonSets",
+			wait: true,
+			err:  "no knet-stress DaemonSets found",
+		},
+		{
+			name: "DaemonSet in another namespace",
+			objs: []runtime.Object{&appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "knet-stress", Namespace: "default", Labels: labels},
+			}},
+			wait: true,
+			err:  "no knet-stress DaemonSets found",
+		},
+		{
+			name: "no pods",
+			err:  "found 0 running knet-stress pods",
+		},
+		{
+			name: "one running pod",
+			objs: []runtime.Object{
+				knetStressPod("knet-stress-1", corev1.PodRunning, "10.0.0.1"),
+				knetStressPod("knet-stress-2", corev1.PodPending, ""),
+				knetStressPod("knet-stress-3", corev1.PodRunning, ""),
+			},
+			err: "found 1 running knet-stress pods",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			destinations := 3
+			cfg := &config.Config{
+				Client: fake.NewSimpleClientset(test.objs...),
+				KnetStress: &config.KnetStress{
+					Namespace:    "knet-stress",
+					Selector:     "app=knet-stress",
+					Destinations: &destinations,
+				},
+			}
+
+			f := New(context.Background(), nil, cfg)
+
+			var err error
+			if test.wait {
+				err = f.WaitKnetStressReady()
+			} else {
+				_, err = f.ProbeKnetStress(context.Background(), 0)
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
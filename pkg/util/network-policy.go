@@ -0,0 +1,191 @@
+package util
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+	"github.com/jetstack/cni-migration/pkg/status"
+)
+
+const (
+	netpolServer  = "netpol-server"
+	netpolAllowed = "netpol-allowed"
+	netpolDenied  = "netpol-denied"
+)
+
+func init() {
+	RegisterCheck("networkPolicy", func(f *Factory) Check {
+		return &networkPolicyCheck{
+			Factory: f,
+			offset:  rand.New(rand.NewSource(time.Now().UnixNano())).Int(),
+		}
+	})
+}
+
+// networkPolicyCheck verifies that NetworkPolicies are enforced, by checking
+// that the allowed client pods can reach a sample of server pods, and the
+// denied client pods cannot. The servers of each client are chosen by offset,
+// so the same flows are probed every time the check is retried. The network
+// policy bundle is deployed by preflight.
+type networkPolicyCheck struct {
+	*Factory
+
+	offset int
+}
+
+func (n *networkPolicyCheck) Name() string {
+	return "networkPolicy"
+}
+
+func (n *networkPolicyCheck) Run() error {
+	cfg := n.config.Checks.NetworkPolicy
+
+	if err := n.ensureNetworkPolicy(); err != nil {
+		return err
+	}
+
+	pods := make(map[string][]corev1.Pod)
+	for _, app := range []string{netpolServer, netpolAllowed, netpolDenied} {
+		list, err := n.client.CoreV1().Pods(cfg.Namespace).List(n.ctx, metav1.ListOptions{
+			LabelSelector: "app=" + app,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, pod := range list.Items {
+			if pod.Status.Phase == corev1.PodRunning && len(pod.Status.PodIP) > 0 {
+				pods[app] = append(pods[app], pod)
+			}
+		}
+
+		if len(pods[app]) == 0 {
+			return fmt.Errorf("no running %s pods in namespace %s", app, cfg.Namespace)
+		}
+	}
+
+	nodePhases, err := n.nodePhases(n.ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, n.config.KnetStress.Concurrency)
+	)
+
+	for _, client := range []string{netpolAllowed, netpolDenied} {
+		allowed := client == netpolAllowed
+
+		for i := range pods[client] {
+			for _, j := range sample(len(pods[netpolServer]), *cfg.Destinations, n.offset+i) {
+				wg.Add(1)
+				sem <- struct{}{}
+
+				go func(src, dst *corev1.Pod) {
+					defer func() {
+						<-sem
+						wg.Done()
+					}()
+
+					address := net.JoinHostPort(dst.Status.PodIP, strconv.Itoa(int(cfg.Port)))
+					err := n.ExecPod(src, replaceAll(cfg.Command, "{address}", address))
+
+					var violation error
+					switch {
+					case allowed && err != nil:
+						violation = fmt.Errorf("allowed flow failed %s: %s", n.flow(src, dst, nodePhases), err)
+					case !allowed && err == nil:
+						violation = fmt.Errorf("denied flow succeeded %s", n.flow(src, dst, nodePhases))
+					}
+
+					if violation != nil {
+						lock.Lock()
+						errs = append(errs, violation)
+						lock.Unlock()
+					}
+				}(&pods[client][i], &pods[netpolServer][j])
+			}
+		}
+	}
+
+	wg.Wait()
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// ensureNetworkPolicy will wait for the DaemonSets of the network policy
+// bundle to become ready, failing if they have not been deployed.
+func (n *networkPolicyCheck) ensureNetworkPolicy() error {
+	resources := n.networkPolicyResources()
+
+	has, err := n.Has(resources)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		return fmt.Errorf("network policy resources not found in namespace %s, run step 0 to create them",
+			n.config.Checks.NetworkPolicy.Namespace)
+	}
+
+	return n.WaitAllReady(resources)
+}
+
+// HasNetworkPolicy returns true if the networkPolicy check is not enabled, or
+// the network policy bundle is deployed.
+func (f *Factory) HasNetworkPolicy() (bool, error) {
+	if !f.networkPolicyEnabled() {
+		return true, nil
+	}
+
+	return f.Has(f.networkPolicyResources())
+}
+
+// CreateNetworkPolicy will deploy the network policy bundle, if the
+// networkPolicy check is enabled.
+func (f *Factory) CreateNetworkPolicy(dryrun bool) error {
+	if !f.networkPolicyEnabled() {
+		return nil
+	}
+
+	f.log.Infof("creating network policy resources")
+	return f.CreateResource(dryrun, f.config.Paths.NetworkPolicy, f.config.Checks.NetworkPolicy.Namespace)
+}
+
+func (f *Factory) networkPolicyEnabled() bool {
+	for _, name := range f.config.Checks.Enabled {
+		if name == "networkPolicy" {
+			return true
+		}
+	}
+	return false
+}
+
+// networkPolicyResources returns the DaemonSets of the network policy bundle.
+func (f *Factory) networkPolicyResources() *config.Resources {
+	return &config.Resources{
+		DaemonSets: map[string][]string{
+			f.config.Checks.NetworkPolicy.Namespace: {netpolServer, netpolAllowed, netpolDenied},
+		},
+	}
+}
+
+// flow returns a description of the flow between two pods, including the CNI
+// and migration phase of each.
+func (n *networkPolicyCheck) flow(src, dst *corev1.Pod, nodePhases map[string]status.Phase) string {
+	s, d := n.probeEndpoint(src, nodePhases), n.probeEndpoint(dst, nodePhases)
+	return fmt.Sprintf("%s (%s, %s, %s) -> %s (%s, %s, %s)",
+		s.Pod, s.Node, s.CNI, s.Phase, d.Pod, d.Node, d.CNI, d.Phase)
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -14,8 +15,7 @@ func (f *Factory) RollNode(dryrun bool, nodeName string, watchResources *config.
 	f.log.Infof("draining node %s", nodeName)
 
 	if !dryrun {
-		args := []string{"kubectl", "drain", "--delete-local-data", "--ignore-daemonsets", nodeName}
-		if err := f.RunCommand(nil, args...); err != nil {
+		if err := f.DrainNode(nodeName); err != nil {
 			return err
 		}
 
@@ -34,18 +34,13 @@ func (f *Factory) RollNode(dryrun bool, nodeName string, watchResources *config.
 
 	f.log.Infof("uncordoning node %s", nodeName)
 	if !dryrun {
-		args := []string{"kubectl", "uncordon", nodeName}
-		if err := f.RunCommand(nil, args...); err != nil {
+		if err := f.UncordonNode(nodeName); err != nil {
 			return err
 		}
 
 		if err := f.WaitAllReady(watchResources); err != nil {
 			return err
 		}
-
-		if err := f.CheckKnetStress(); err != nil {
-			return err
-		}
 	}
 
 	return nil
@@ -95,7 +90,12 @@ func (f *Factory) DeletePodsOnNode(nodeName string) error {
 			break
 		}
 
-		time.Sleep(time.Second)
+		select {
+		case <-f.ctx.Done():
+			return fmt.Errorf("waiting for %d pods on node %s to be deleted: %s",
+				len(toBeDeleted), nodeName, f.ctx.Err())
+		case <-time.After(time.Second):
+		}
 	}
 
 	return nil
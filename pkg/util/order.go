@@ -0,0 +1,103 @@
+package util
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// nodeGroupKey identifies a set of nodes in the same zone and node pool,
+// which may be processed together.
+type nodeGroupKey struct {
+	controlPlane bool
+	zone         string
+	pool         string
+}
+
+type nodeGroup struct {
+	nodeGroupKey
+	nodeNames []string
+}
+
+// OrderNodes will order the given nodes into groups according to the
+// configured ordering. The canary node, if given, is always in its own first
+// group. Remaining nodes are grouped by zone and node pool, sorted by name,
+// with control plane nodes last. Nodes from different groups are never
+// processed at the same time.
+func (f *Factory) OrderNodes(nodes []corev1.Node) [][]string {
+	ordering := f.config.Ordering
+
+	var (
+		groups []*nodeGroup
+		canary []string
+	)
+
+	groupIndex := make(map[nodeGroupKey]*nodeGroup)
+
+	for _, node := range nodes {
+		if len(ordering.Canary) > 0 && node.Name == ordering.Canary {
+			canary = []string{node.Name}
+			continue
+		}
+
+		key := nodeGroupKey{
+			controlPlane: isControlPlane(ordering.ControlPlaneLabels, &node),
+			zone:         node.Labels[ordering.ZoneLabel],
+			pool:         nodePool(ordering.NodePoolLabels, &node),
+		}
+
+		group, ok := groupIndex[key]
+		if !ok {
+			group = &nodeGroup{nodeGroupKey: key}
+			groupIndex[key] = group
+			groups = append(groups, group)
+		}
+
+		group.nodeNames = append(group.nodeNames, node.Name)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.controlPlane != b.controlPlane {
+			return !a.controlPlane
+		}
+		if a.zone != b.zone {
+			return a.zone < b.zone
+		}
+		return a.pool < b.pool
+	})
+
+	var ordered [][]string
+	if canary != nil {
+		ordered = append(ordered, canary)
+	}
+
+	for _, group := range groups {
+		sort.Strings(group.nodeNames)
+		ordered = append(ordered, group.nodeNames)
+	}
+
+	return ordered
+}
+
+func isControlPlane(labels []string, node *corev1.Node) bool {
+	for _, label := range labels {
+		if _, ok := node.Labels[label]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nodePool returns the value of the first node pool label present on the
+// node.
+func nodePool(labels []string, node *corev1.Node) string {
+	for _, label := range labels {
+		if v, ok := node.Labels[label]; ok {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,119 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+func testNode(name string, labels map[string]string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+func TestOrderNodes(t *testing.T) {
+	const (
+		zone   = "topology.kubernetes.io/zone"
+		pool   = "cloud.google.com/gke-nodepool"
+		master = "node-role.kubernetes.io/master"
+	)
+
+	ordering := config.Ordering{
+		ZoneLabel:          zone,
+		NodePoolLabels:     []string{pool, "eks.amazonaws.com/nodegroup"},
+		ControlPlaneLabels: []string{master},
+	}
+
+	for _, test := range []struct {
+		name   string
+		canary string
+		nodes  []corev1.Node
+		want   [][]string
+	}{
+		{
+			name: "no nodes",
+		},
+		{
+			name: "unlabelled nodes sorted by name",
+			nodes: []corev1.Node{
+				testNode("node-c", nil),
+				testNode("node-a", nil),
+				testNode("node-b", nil),
+			},
+			want: [][]string{{"node-a", "node-b", "node-c"}},
+		},
+		{
+			name: "grouped by zone",
+			nodes: []corev1.Node{
+				testNode("node-1", map[string]string{zone: "b"}),
+				testNode("node-2", map[string]string{zone: "a"}),
+				testNode("node-3", map[string]string{zone: "b"}),
+			},
+			want: [][]string{{"node-2"}, {"node-1", "node-3"}},
+		},
+		{
+			name: "grouped by zone then pool",
+			nodes: []corev1.Node{
+				testNode("node-1", map[string]string{zone: "a", pool: "web"}),
+				testNode("node-2", map[string]string{zone: "a", pool: "db"}),
+				testNode("node-3", map[string]string{zone: "b", pool: "db"}),
+				testNode("node-4", map[string]string{zone: "a", pool: "web"}),
+			},
+			want: [][]string{{"node-2"}, {"node-1", "node-4"}, {"node-3"}},
+		},
+		{
+			name: "first pool label present is used",
+			nodes: []corev1.Node{
+				testNode("node-1", map[string]string{"eks.amazonaws.com/nodegroup": "web"}),
+				testNode("node-2", map[string]string{pool: "web", "eks.amazonaws.com/nodegroup": "db"}),
+			},
+			want: [][]string{{"node-1", "node-2"}},
+		},
+		{
+			name: "control plane last",
+			nodes: []corev1.Node{
+				testNode("master-1", map[string]string{master: "", zone: "a"}),
+				testNode("node-1", map[string]string{zone: "b"}),
+				testNode("master-2", map[string]string{master: "", zone: "a"}),
+			},
+			want: [][]string{{"node-1"}, {"master-1", "master-2"}},
+		},
+		{
+			name:   "canary first and alone",
+			canary: "node-3",
+			nodes: []corev1.Node{
+				testNode("node-1", map[string]string{zone: "a"}),
+				testNode("node-2", map[string]string{zone: "a"}),
+				testNode("node-3", map[string]string{zone: "a"}),
+			},
+			want: [][]string{{"node-3"}, {"node-1", "node-2"}},
+		},
+		{
+			name:   "canary not in nodes",
+			canary: "node-9",
+			nodes: []corev1.Node{
+				testNode("node-1", nil),
+			},
+			want: [][]string{{"node-1"}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			o := ordering
+			o.Canary = test.canary
+
+			f := &Factory{config: &config.Config{Ordering: &o}}
+
+			if got := f.OrderNodes(test.nodes); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
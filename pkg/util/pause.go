@@ -0,0 +1,124 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+
+	"github.com/jetstack/cni-migration/pkg/status"
+)
+
+const (
+	// ContextPauseKey holds the *Pause shared by all steps of a run.
+	ContextPauseKey = "cni-migration-pause"
+)
+
+// ErrPaused is returned once the configured number of nodes have been
+// processed.
+var ErrPaused = errors.New("paused")
+
+// Action is the operator's choice after a batch of nodes has been processed.
+type Action string
+
+const (
+	ActionContinue Action = "continue"
+	ActionSkip     Action = "skip"
+	ActionRetry    Action = "retry"
+	ActionAbort    Action = "abort"
+)
+
+// Pause controls pausing between nodes of the per node steps. It is shared
+// between all steps, so PauseAfter counts nodes across the whole run.
+type Pause struct {
+	// Interactive will prompt the operator after every batch of nodes.
+	Interactive bool
+
+	// PauseAfter will stop the run once this many nodes have been
+	// processed. Zero never pauses.
+	PauseAfter int
+
+	In  *bufio.Reader
+	Out io.Writer
+
+	processed int
+}
+
+// batchSummary is printed to the operator after a batch of nodes.
+type batchSummary struct {
+	node       string
+	podsRolled int
+	phase      status.Phase
+	err        error
+}
+
+// pauseFromContext returns the pause of the run, or nil if not set.
+func (f *Factory) pauseFromContext() *Pause {
+	p, _ := f.ctx.Value(ContextPauseKey).(*Pause)
+	return p
+}
+
+// podsOnNode returns the number of pods on the node which are not using the
+// host network, and so will be rescheduled when the node is processed.
+func (f *Factory) podsOnNode(nodeName string) (int, error) {
+	pods, err := f.client.CoreV1().Pods("").List(f.ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, pod := range pods.Items {
+		if !pod.Spec.HostNetwork && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// prompt will print the summary of the batch, along with the connectivity
+// result, and ask the operator what to do next. next is the list of nodes in
+// the following batch, if any.
+func (p *Pause) prompt(summaries []batchSummary, connectivity string, next []string) (Action, error) {
+	fmt.Fprintln(p.Out)
+	for _, s := range summaries {
+		result := "ok"
+		if s.err != nil {
+			result = s.err.Error()
+		}
+		fmt.Fprintf(p.Out, "node %s: phase=%s pods-rescheduled=%d result=%s\n", s.node, s.phase, s.podsRolled, result)
+	}
+
+	fmt.Fprintf(p.Out, "connectivity: %s\n", connectivity)
+
+	if len(next) > 0 {
+		fmt.Fprintf(p.Out, "next: %s\n", strings.Join(next, ", "))
+	}
+
+	for {
+		fmt.Fprintf(p.Out, "[c]ontinue, [s]kip next, [r]etry, [a]bort? ")
+
+		line, err := p.In.ReadString('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return ActionAbort, fmt.Errorf("failed to read response: %s", err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "c", "continue":
+			return ActionContinue, nil
+		case "s", "skip":
+			return ActionSkip, nil
+		case "r", "retry":
+			return ActionRetry, nil
+		case "a", "abort":
+			return ActionAbort, nil
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DisruptionIssue is a workload which would block, or lose availability
+// during, the drain of the nodes its pods run on.
+type DisruptionIssue struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Nodes     []string
+
+	// Blocking is true if draining the nodes would block on this workload.
+	Blocking bool
+	Reason   string
+}
+
+// AnalyzeDisruptions will match every PodDisruptionBudget against the pods
+// running on every node, and return the workloads which would block a drain,
+// such as unmanaged pods and budgets allowing no disruptions, along with
+// single replica Deployments and StatefulSets which would be unavailable
+// during a drain.
+func (f *Factory) AnalyzeDisruptions() ([]DisruptionIssue, error) {
+	pods, err := f.client.CoreV1().Pods(metav1.NamespaceAll).List(f.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		issues    []DisruptionIssue
+		evictable []corev1.Pod
+	)
+
+	for _, pod := range pods.Items {
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if len(pod.Spec.NodeName) == 0 {
+			continue
+		}
+
+		controller := metav1.GetControllerOf(&pod)
+		if controller != nil && controller.Kind == "DaemonSet" {
+			continue
+		}
+
+		if controller == nil {
+			issues = append(issues, DisruptionIssue{
+				Kind:      "Pod",
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+				Nodes:     []string{pod.Spec.NodeName},
+				Blocking:  true,
+				Reason:    "not managed by a controller",
+			})
+			continue
+		}
+
+		evictable = append(evictable, pod)
+	}
+
+	pdbs, err := f.client.PolicyV1beta1().PodDisruptionBudgets(metav1.NamespaceAll).List(f.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pdb := range pdbs.Items {
+		if pdb.Spec.Selector == nil {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes := nodesOfPods(evictable, pdb.Namespace, selector)
+		if len(nodes) == 0 {
+			continue
+		}
+
+		if pdb.Status.DisruptionsAllowed == 0 {
+			issues = append(issues, DisruptionIssue{
+				Kind:      "PodDisruptionBudget",
+				Namespace: pdb.Namespace,
+				Name:      pdb.Name,
+				Nodes:     nodes,
+				Blocking:  true,
+				Reason: fmt.Sprintf("no disruptions allowed (%d/%d pods healthy, %d desired)",
+					pdb.Status.CurrentHealthy, pdb.Status.ExpectedPods, pdb.Status.DesiredHealthy),
+			})
+		}
+	}
+
+	deploys, err := f.client.AppsV1().Deployments(metav1.NamespaceAll).List(f.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, deploy := range deploys.Items {
+		if deploy.Spec.Replicas == nil || *deploy.Spec.Replicas != 1 {
+			continue
+		}
+
+		if issue, err := singleReplicaIssue(evictable, "Deployment", deploy.Namespace, deploy.Name, deploy.Spec.Selector); err != nil {
+			return nil, err
+		} else if issue != nil {
+			issues = append(issues, *issue)
+		}
+	}
+
+	statefulSets, err := f.client.AppsV1().StatefulSets(metav1.NamespaceAll).List(f.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sts := range statefulSets.Items {
+		if sts.Spec.Replicas == nil || *sts.Spec.Replicas != 1 {
+			continue
+		}
+
+		if issue, err := singleReplicaIssue(evictable, "StatefulSet", sts.Namespace, sts.Name, sts.Spec.Selector); err != nil {
+			return nil, err
+		} else if issue != nil {
+			issues = append(issues, *issue)
+		}
+	}
+
+	return issues, nil
+}
+
+func singleReplicaIssue(pods []corev1.Pod, kind, namespace, name string, labelSelector *metav1.LabelSelector) (*DisruptionIssue, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := nodesOfPods(pods, namespace, selector)
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	return &DisruptionIssue{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Nodes:     nodes,
+		Reason:    "single replica, unavailable while its node is drained",
+	}, nil
+}
+
+// nodesOfPods returns the sorted names of the nodes running pods in the
+// namespace matching the selector.
+func nodesOfPods(pods []corev1.Pod, namespace string, selector labels.Selector) []string {
+	found := make(map[string]bool)
+	for _, pod := range pods {
+		if pod.Namespace == namespace && selector.Matches(labels.Set(pod.Labels)) {
+			found[pod.Spec.NodeName] = true
+		}
+	}
+
+	var nodes []string
+	for node := range found {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	return nodes
+}
@@ -0,0 +1,712 @@
+package util
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	prerequisitesName = "cni-migration-prerequisites"
+	imagePullName     = "cni-migration-image-pull"
+
+	// imagePullCommand does not exist in any image, so that containers of the
+	// image pull DaemonSet fail to start once their image has been pulled,
+	// without running anything.
+	imagePullCommand = "/cni-migration-image-pull-check"
+
+	// geneveReceived is the file in each prerequisites pod which all UDP
+	// datagrams received on the Geneve port are written to.
+	geneveReceived = "/tmp/geneve"
+)
+
+// PrerequisiteResult is the result of a single prerequisite check, either of
+// the cluster, a node or a pair of nodes.
+type PrerequisiteResult struct {
+	Check   string `json:"check" yaml:"check"`
+	Node    string `json:"node,omitempty" yaml:"node,omitempty"`
+	Passed  bool   `json:"passed" yaml:"passed"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// PrerequisitesReport holds the results of all prerequisite checks.
+type PrerequisitesReport struct {
+	Results []PrerequisiteResult `json:"results" yaml:"results"`
+}
+
+// Failed returns all results which did not pass.
+func (p *PrerequisitesReport) Failed() []PrerequisiteResult {
+	var failed []PrerequisiteResult
+	for _, r := range p.Results {
+		if !r.Passed {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+func (p *PrerequisitesReport) add(check, node string, passed bool, format string, args ...interface{}) {
+	p.Results = append(p.Results, PrerequisiteResult{
+		Check:   check,
+		Node:    node,
+		Passed:  passed,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// CheckPrerequisites will check the cluster meets the requirements of the
+// migration, returning a report of every check. The kernel, bpf and geneve
+// checks run from a short lived privileged DaemonSet, and the images check
+// from a DaemonSet of every image in the bundles, which are deleted once
+// complete. No DaemonSets are deployed during a dry run, so only the
+// Kubernetes version is checked.
+func (f *Factory) CheckPrerequisites(dryrun bool) (*PrerequisitesReport, error) {
+	cfg := f.config.Prerequisites
+
+	enabled := make(map[string]bool)
+	for _, name := range cfg.Enabled {
+		enabled[name] = true
+	}
+
+	report := new(PrerequisitesReport)
+
+	// Without a tunnel, Cilium does not use the Geneve port.
+	if enabled["geneve"] && f.config.Bundles.TunnelMode == "disabled" {
+		delete(enabled, "geneve")
+		report.add("geneve", "", true, "not required, bundles tunnel mode is disabled")
+	}
+
+	if enabled["kubernetesVersion"] {
+		if err := f.checkKubernetesVersion(report); err != nil {
+			return nil, err
+		}
+	}
+
+	nodeChecks := enabled["kernel"] || enabled["bpf"] || enabled["geneve"]
+	if !nodeChecks && !enabled["images"] {
+		return report, nil
+	}
+
+	if dryrun {
+		f.log.Infof("skipping node and image prerequisite checks during dry run, requires DaemonSets in namespace %s", cfg.Namespace)
+		return report, nil
+	}
+
+	if err := f.createPrerequisitesNamespace(); err != nil {
+		return nil, err
+	}
+	defer f.deletePrerequisitesNamespace()
+
+	err := f.withTimeout(cfg.Timeout, "prerequisites check", func(f *Factory) error {
+		if nodeChecks {
+			if err := f.checkNodePrerequisites(report, enabled); err != nil {
+				return err
+			}
+		}
+
+		if enabled["images"] {
+			return f.checkImages(report)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// checkKubernetesVersion will check the server version is within the
+// supported range. Only the major and minor versions are compared against the
+// maximum.
+func (f *Factory) checkKubernetesVersion(report *PrerequisitesReport) error {
+	cfg := f.config.Prerequisites
+
+	info, err := f.client.Discovery().ServerVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get kubernetes server version: %s", err)
+	}
+
+	server, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return fmt.Errorf("failed to parse kubernetes server version %q: %s", info.GitVersion, err)
+	}
+
+	min, err := version.ParseGeneric(cfg.MinKubernetesVersion)
+	if err != nil {
+		return fmt.Errorf("failed to parse minimum kubernetes version %q: %s", cfg.MinKubernetesVersion, err)
+	}
+
+	max, err := version.ParseGeneric(cfg.MaxKubernetesVersion)
+	if err != nil {
+		return fmt.Errorf("failed to parse maximum kubernetes version %q: %s", cfg.MaxKubernetesVersion, err)
+	}
+
+	minor := version.MustParseGeneric(fmt.Sprintf("%d.%d", server.Major(), server.Minor()))
+
+	switch {
+	case server.LessThan(min):
+		report.add("kubernetesVersion", "", false, "server version %s is older than the minimum supported %s",
+			info.GitVersion, cfg.MinKubernetesVersion)
+	case max.LessThan(minor):
+		report.add("kubernetesVersion", "", false, "server version %s is newer than the maximum supported %s",
+			info.GitVersion, cfg.MaxKubernetesVersion)
+	default:
+		report.add("kubernetesVersion", "", true, "server version %s", info.GitVersion)
+	}
+
+	return nil
+}
+
+// checkNodePrerequisites will deploy the prerequisites DaemonSet, and run the
+// enabled kernel, bpf and geneve checks from its pods.
+func (f *Factory) checkNodePrerequisites(report *PrerequisitesReport, enabled map[string]bool) error {
+	cfg := f.config.Prerequisites
+
+	f.log.Infof("deploying daemonset %s/%s", cfg.Namespace, prerequisitesName)
+
+	_, err := f.client.AppsV1().DaemonSets(cfg.Namespace).Create(f.ctx, f.prerequisitesDaemonSet(), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create daemonset %s/%s: %s", cfg.Namespace, prerequisitesName, err)
+	}
+
+	if err := f.WaitDaemonSetReady(cfg.Namespace, prerequisitesName); err != nil {
+		return err
+	}
+
+	pods, err := f.podPerNode(cfg.Namespace, "app="+prerequisitesName)
+	if err != nil {
+		return err
+	}
+
+	if enabled["kernel"] {
+		min, err := version.ParseGeneric(cfg.MinKernelVersion)
+		if err != nil {
+			return fmt.Errorf("failed to parse minimum kernel version %q: %s", cfg.MinKernelVersion, err)
+		}
+
+		for i := range pods {
+			f.checkKernel(report, &pods[i], min)
+		}
+	}
+
+	if enabled["bpf"] {
+		for i := range pods {
+			f.checkBPF(report, &pods[i])
+		}
+	}
+
+	if enabled["geneve"] {
+		f.checkGeneve(report, pods)
+	}
+
+	return nil
+}
+
+func (f *Factory) checkKernel(report *PrerequisitesReport, pod *corev1.Pod, min *version.Version) {
+	node := pod.Spec.NodeName
+
+	out, err := f.execPodOutput(f.ctx, pod, "", []string{"uname", "-r"})
+	if err != nil {
+		report.add("kernel", node, false, "failed to get kernel version: %s", err)
+		return
+	}
+
+	release := strings.TrimSpace(out)
+	kernel, err := version.ParseGeneric(release)
+	if err != nil {
+		report.add("kernel", node, false, "failed to parse kernel version %q: %s", release, err)
+		return
+	}
+
+	if kernel.LessThan(min) {
+		report.add("kernel", node, false, "kernel %s is older than the minimum supported %s", release, min)
+		return
+	}
+
+	report.add("kernel", node, true, "kernel %s", release)
+}
+
+// checkBPF will check the BPF filesystem is mounted on the host, or at least
+// supported by the kernel so that Cilium can mount it.
+func (f *Factory) checkBPF(report *PrerequisitesReport, pod *corev1.Pod) {
+	node := pod.Spec.NodeName
+
+	script := `if grep -q " /sys/fs/bpf bpf " /proc/1/mounts; then echo mounted; ` +
+		`elif grep -qw bpf /proc/filesystems; then echo supported; else echo unsupported; fi`
+
+	out, err := f.execPodOutput(f.ctx, pod, "", []string{"sh", "-c", script})
+	if err != nil {
+		report.add("bpf", node, false, "failed to check bpf filesystem: %s", err)
+		return
+	}
+
+	switch strings.TrimSpace(out) {
+	case "mounted":
+		report.add("bpf", node, true, "bpf filesystem mounted at /sys/fs/bpf")
+	case "supported":
+		report.add("bpf", node, true, "bpf filesystem supported but not mounted at /sys/fs/bpf, cilium will mount it")
+	default:
+		report.add("bpf", node, false, "bpf filesystem not supported by the kernel")
+	}
+}
+
+// checkGeneve will send UDP datagrams to the Geneve port of the configured
+// number of peers from every node. Every node receives from a single other
+// node in each round, since a listener only accepts datagrams from its first
+// peer until it is restarted, so there is a round for every peer. The peers
+// are spread evenly across the nodes, and sampled differently on every run.
+func (f *Factory) checkGeneve(report *PrerequisitesReport, pods []corev1.Pod) {
+	var (
+		n    = len(pods)
+		sem  = make(chan struct{}, f.config.KnetStress.Concurrency)
+		seed = rand.New(rand.NewSource(time.Now().UnixNano())).Int()
+	)
+
+	// Each round is the offset of the peer of every node.
+	for _, round := range probeDestinations(0, n, *f.config.Prerequisites.GenevePeers, seed) {
+		var wg sync.WaitGroup
+		results := make([]PrerequisiteResult, n)
+
+		for i := range pods {
+			wg.Add(1)
+			sem <- struct{}{}
+
+			go func(i int) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				results[i] = f.geneveProbe(&pods[i], &pods[(i+round)%n])
+			}(i)
+		}
+
+		wg.Wait()
+
+		report.Results = append(report.Results, results...)
+	}
+}
+
+func (f *Factory) geneveProbe(src, dst *corev1.Pod) PrerequisiteResult {
+	port := f.config.Prerequisites.GenevePort
+	token := fmt.Sprintf("cni-migration-%s-%s", src.Spec.NodeName, dst.Spec.NodeName)
+
+	result := PrerequisiteResult{
+		Check: "geneve",
+		Node:  src.Spec.NodeName + " -> " + dst.Spec.NodeName,
+	}
+
+	send := fmt.Sprintf("for i in 1 2 3 4 5; do echo %s | nc -u -w 1 %s %d; done; exit 0",
+		token, dst.Status.HostIP, port)
+
+	if err := f.execPod(f.ctx, src, "", []string{"sh", "-c", send}); err != nil {
+		result.Message = fmt.Sprintf("failed to send to udp/%d: %s", port, err)
+		return result
+	}
+
+	if err := f.execPod(f.ctx, dst, "", []string{"grep", "-qF", token, geneveReceived}); err != nil {
+		result.Message = fmt.Sprintf("no datagrams received on udp/%d from %s", port, src.Status.HostIP)
+		return result
+	}
+
+	result.Passed = true
+	result.Message = fmt.Sprintf("udp/%d reachable", port)
+
+	return result
+}
+
+// checkImages will deploy a DaemonSet of every image referenced in the
+// bundles, and wait for every image to be pulled, or fail to be pulled, on
+// every node. If the timeout is reached, every image not yet pulled fails,
+// including on nodes which never ran an image pull pod.
+func (f *Factory) checkImages(report *PrerequisitesReport) error {
+	cfg := f.config.Prerequisites
+
+	images, err := f.bundleImages()
+	if err != nil {
+		return err
+	}
+
+	if len(images) == 0 {
+		return nil
+	}
+
+	// The nodes are listed up front, since the context is done by the time
+	// nodes which never reported need to be found.
+	nodes, err := f.client.CoreV1().Nodes().List(f.ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	f.log.Infof("deploying daemonset %s/%s to pull %d images", cfg.Namespace, imagePullName, len(images))
+
+	_, err = f.client.AppsV1().DaemonSets(cfg.Namespace).Create(f.ctx, imagePullDaemonSet(cfg.Namespace, images, f.imagePullSecrets()), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create daemonset %s/%s: %s", cfg.Namespace, imagePullName, err)
+	}
+
+	ticker := time.NewTicker(f.config.KnetStress.Interval)
+	defer ticker.Stop()
+
+	for {
+		results, pending, err := f.imagePullResults()
+		if err != nil {
+			return err
+		}
+
+		if pending == 0 {
+			report.Results = append(report.Results, results...)
+			return nil
+		}
+
+		f.log.Debugf("waiting for %d images to be pulled", pending)
+
+		select {
+		case <-f.ctx.Done():
+			reported := make(map[string]bool)
+			for _, r := range results {
+				if !r.Passed && len(r.Message) == 0 {
+					r.Message = fmt.Sprintf("not pulled: %s", f.ctx.Err())
+				}
+				report.Results = append(report.Results, r)
+				reported[r.Node] = true
+			}
+
+			for _, node := range nodes.Items {
+				if reported[node.Name] {
+					continue
+				}
+				for _, image := range images {
+					report.add("images", node.Name, false, "%s: not pulled, no image pull pod ran on the node: %s", image, f.ctx.Err())
+				}
+			}
+
+			return nil
+		case <-ticker.C:
+			continue
+		}
+	}
+}
+
+// imagePullResults returns the pull result of every image on every node
+// running an image pull pod, along with the number of pulls not yet complete.
+// Pending results have no message.
+func (f *Factory) imagePullResults() ([]PrerequisiteResult, int, error) {
+	ns := f.config.Prerequisites.Namespace
+
+	ds, err := f.client.AppsV1().DaemonSets(ns).Get(f.ctx, imagePullName, metav1.GetOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pods, err := f.client.CoreV1().Pods(ns).List(f.ctx, metav1.ListOptions{
+		LabelSelector: "app=" + imagePullName,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		results []PrerequisiteResult
+		pending int
+	)
+
+	if int32(len(pods.Items)) < ds.Status.DesiredNumberScheduled || ds.Status.DesiredNumberScheduled == 0 {
+		pending++
+	}
+
+	for _, pod := range pods.Items {
+		statuses := make(map[string]corev1.ContainerStatus)
+		for _, status := range pod.Status.ContainerStatuses {
+			statuses[status.Name] = status
+		}
+
+		for _, container := range pod.Spec.Containers {
+			result := PrerequisiteResult{
+				Check: "images",
+				Node:  pod.Spec.NodeName,
+			}
+
+			done, pulled, message := imagePulled(statuses[container.Name])
+			if !done {
+				pending++
+			} else {
+				result.Passed = pulled
+				result.Message = fmt.Sprintf("%s: %s", container.Image, message)
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Node < results[j].Node
+	})
+
+	return results, pending, nil
+}
+
+// imagePulled returns whether the container has finished pulling its image,
+// and if so, whether the pull succeeded. The image is only known to be pulled
+// once the container has started, or failed to start since the command does
+// not exist. Any other waiting reason is a failure.
+func imagePulled(status corev1.ContainerStatus) (bool, bool, string) {
+	switch {
+	case status.State.Running != nil, status.State.Terminated != nil, status.LastTerminationState.Terminated != nil:
+		return true, true, "pulled"
+
+	case status.State.Waiting != nil:
+		switch reason := status.State.Waiting.Reason; reason {
+		case "", "ContainerCreating", "PodInitializing":
+			return false, false, ""
+
+		case "RunContainerError", "CrashLoopBackOff":
+			// The container was created from the image, but failed to start
+			// since the command does not exist.
+			return true, true, "pulled"
+
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull", "RegistryUnavailable":
+			return true, false, fmt.Sprintf("%s: %s", reason, status.State.Waiting.Message)
+
+		default:
+			return true, false, fmt.Sprintf("not known to be pulled, %s: %s", reason, status.State.Waiting.Message)
+		}
+	}
+
+	return false, false, ""
+}
+
+// bundleImages returns every container image referenced by the workloads and
+// pods of the bundles.
+func (f *Factory) bundleImages() ([]string, error) {
+	found := make(map[string]bool)
+
+	for _, path := range []string{f.config.Paths.Cilium, f.config.Paths.Multus, f.config.Paths.KnetStress, f.config.Paths.NetworkPolicy} {
+		if len(path) == 0 {
+			continue
+		}
+
+		objs, err := f.config.ReadBundle(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range objs {
+			for _, field := range [][]string{
+				{"spec", "template", "spec", "containers"},
+				{"spec", "template", "spec", "initContainers"},
+				{"spec", "containers"},
+				{"spec", "initContainers"},
+			} {
+				containers, ok, err := unstructured.NestedSlice(obj.Object, field...)
+				if err != nil || !ok {
+					continue
+				}
+
+				for _, c := range containers {
+					container, ok := c.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					if image, ok := container["image"].(string); ok && len(image) > 0 {
+						found[image] = true
+					}
+				}
+			}
+		}
+	}
+
+	var images []string
+	for image := range found {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	return images, nil
+}
+
+func (f *Factory) createPrerequisitesNamespace() error {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: f.config.Prerequisites.Namespace,
+		},
+	}
+
+	_, err := f.client.CoreV1().Namespaces().Create(f.ctx, ns, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create namespace %s: %s", ns.Name, err)
+	}
+
+	return f.copyImagePullSecrets()
+}
+
+// copyImagePullSecrets will copy every configured image pull secret into the
+// prerequisites namespace, so that they are deleted along with it.
+func (f *Factory) copyImagePullSecrets() error {
+	ns := f.config.Prerequisites.Namespace
+
+	for _, ref := range f.config.Prerequisites.ImagePullSecrets {
+		parts := strings.SplitN(ref, "/", 2)
+
+		secret, err := f.client.CoreV1().Secrets(parts[0]).Get(f.ctx, parts[1], metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get image pull secret %s: %s", ref, err)
+		}
+
+		cp := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secret.Name,
+				Namespace: ns,
+			},
+			Type: secret.Type,
+			Data: secret.Data,
+		}
+
+		_, err = f.client.CoreV1().Secrets(ns).Create(f.ctx, cp, metav1.CreateOptions{})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to copy image pull secret %s to namespace %s: %s", ref, ns, err)
+		}
+	}
+
+	return nil
+}
+
+// imagePullSecrets returns the references to the image pull secrets copied
+// into the prerequisites namespace.
+func (f *Factory) imagePullSecrets() []corev1.LocalObjectReference {
+	var refs []corev1.LocalObjectReference
+	for _, ref := range f.config.Prerequisites.ImagePullSecrets {
+		refs = append(refs, corev1.LocalObjectReference{
+			Name: ref[strings.Index(ref, "/")+1:],
+		})
+	}
+	return refs
+}
+
+// deletePrerequisitesNamespace will delete the namespace of the prerequisite
+// checks, and wait for it to be removed so that the checks can be run again.
+func (f *Factory) deletePrerequisitesNamespace() {
+	ns := f.config.Prerequisites.Namespace
+
+	f.log.Infof("deleting namespace %s", ns)
+
+	policy := metav1.DeletePropagationBackground
+	err := f.client.CoreV1().Namespaces().Delete(f.ctx, ns, metav1.DeleteOptions{
+		PropagationPolicy: &policy,
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		f.log.Errorf("failed to delete namespace %s: %s", ns, err)
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		_, err := f.client.CoreV1().Namespaces().Get(f.ctx, ns, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return
+		}
+		if err != nil {
+			f.log.Errorf("failed to wait for namespace %s to be deleted: %s", ns, err)
+			return
+		}
+
+		select {
+		case <-f.ctx.Done():
+			f.log.Errorf("failed to wait for namespace %s to be deleted: %s", ns, f.ctx.Err())
+			return
+		case <-ticker.C:
+			continue
+		}
+	}
+}
+
+// prerequisitesDaemonSet runs a privileged pod on the host network and PID
+// namespace of every node, listening on the Geneve port.
+func (f *Factory) prerequisitesDaemonSet() *appsv1.DaemonSet {
+	cfg := f.config.Prerequisites
+
+	privileged := true
+	listen := fmt.Sprintf("touch %s; while true; do timeout 2 nc -u -l -p %d >> %s || sleep 1; done",
+		geneveReceived, cfg.GenevePort, geneveReceived)
+
+	return daemonSet(cfg.Namespace, prerequisitesName, corev1.PodSpec{
+		HostNetwork:      true,
+		HostPID:          true,
+		ImagePullSecrets: f.imagePullSecrets(),
+		Containers: []corev1.Container{
+			{
+				Name:    "check",
+				Image:   cfg.Image,
+				Command: []string{"sh", "-c", listen},
+				SecurityContext: &corev1.SecurityContext{
+					Privileged: &privileged,
+				},
+			},
+		},
+	})
+}
+
+// imagePullDaemonSet runs a container of every image on every node, pulled
+// with the image pull secrets. The containers never start, since the command
+// does not exist.
+func imagePullDaemonSet(namespace string, images []string, secrets []corev1.LocalObjectReference) *appsv1.DaemonSet {
+	var containers []corev1.Container
+	for i, image := range images {
+		containers = append(containers, corev1.Container{
+			Name:            fmt.Sprintf("image-%d", i),
+			Image:           image,
+			Command:         []string{imagePullCommand},
+			ImagePullPolicy: corev1.PullIfNotPresent,
+		})
+	}
+
+	return daemonSet(namespace, imagePullName, corev1.PodSpec{
+		Containers:       containers,
+		ImagePullSecrets: secrets,
+	})
+}
+
+// daemonSet builds a DaemonSet of the pod spec which tolerates all taints, so
+// that it runs on every node.
+func daemonSet(namespace, name string, spec corev1.PodSpec) *appsv1.DaemonSet {
+	labels := map[string]string{"app": name}
+
+	var gracePeriod int64
+	spec.TerminationGracePeriodSeconds = &gracePeriod
+	spec.Tolerations = []corev1.Toleration{
+		{Operator: corev1.TolerationOpExists},
+	}
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: spec,
+			},
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestImagePulled(t *testing.T) {
+	waiting := func(reason string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: reason},
+			},
+		}
+	}
+
+	for _, test := range []struct {
+		name         string
+		status       corev1.ContainerStatus
+		done, pulled bool
+	}{
+		{"no status", corev1.ContainerStatus{}, false, false},
+		{"creating", waiting("ContainerCreating"), false, false},
+		{"running", corev1.ContainerStatus{State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}, true, true},
+		{"terminated", corev1.ContainerStatus{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "StartError"}}}, true, true},
+		{"previously terminated", corev1.ContainerStatus{
+			State:                waiting("ContainerCreating").State,
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+		}, true, true},
+		{"run error", waiting("RunContainerError"), true, true},
+		{"crash loop", waiting("CrashLoopBackOff"), true, true},
+		{"pull error", waiting("ErrImagePull"), true, false},
+		{"pull back off", waiting("ImagePullBackOff"), true, false},
+		{"create error", waiting("CreateContainerError"), true, false},
+		{"create config error", waiting("CreateContainerConfigError"), true, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			done, pulled, _ := imagePulled(test.status)
+			if done != test.done || pulled != test.pulled {
+				t.Errorf("got done=%t pulled=%t, want done=%t pulled=%t", done, pulled, test.done, test.pulled)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// PairSummary is the number of probes, and failed probes, between a source and
+// destination, either nodes or migration phases.
+type PairSummary struct {
+	Source      string `json:"source" yaml:"source"`
+	Destination string `json:"destination" yaml:"destination"`
+	Total       int    `json:"total" yaml:"total"`
+	Failed      int    `json:"failed" yaml:"failed"`
+}
+
+// ConnectivityReport is the full probe matrix, summarised by node pair and by
+// migration phase pair, so that a single broken node can be told apart from
+// broken connectivity between migration phases.
+type ConnectivityReport struct {
+	Results    []ProbeResult `json:"results" yaml:"results"`
+	NodePairs  []PairSummary `json:"nodePairs" yaml:"nodePairs"`
+	PhasePairs []PairSummary `json:"phasePairs" yaml:"phasePairs"`
+}
+
+// Report builds the connectivity report of the probe matrix.
+func (p *ProbeMatrix) Report() *ConnectivityReport {
+	nodePairs := make(map[[2]string]*PairSummary)
+	phasePairs := make(map[[2]string]*PairSummary)
+
+	add := func(pairs map[[2]string]*PairSummary, src, dst string, ok bool) {
+		key := [2]string{src, dst}
+		summary, exists := pairs[key]
+		if !exists {
+			summary = &PairSummary{Source: src, Destination: dst}
+			pairs[key] = summary
+		}
+
+		summary.Total++
+		if !ok {
+			summary.Failed++
+		}
+	}
+
+	for _, r := range p.Results {
+		add(nodePairs, r.Source.Node, r.Destination.Node, r.OK)
+		add(phasePairs, string(r.Source.Phase), string(r.Destination.Phase), r.OK)
+	}
+
+	return &ConnectivityReport{
+		Results:    p.Results,
+		NodePairs:  sortedPairs(nodePairs),
+		PhasePairs: sortedPairs(phasePairs),
+	}
+}
+
+// FailingNodePairs returns all node pairs with at least one failed probe.
+func (c *ConnectivityReport) FailingNodePairs() []PairSummary {
+	return failingPairs(c.NodePairs)
+}
+
+// FailingPhasePairs returns all migration phase pairs with at least one
+// failed probe.
+func (c *ConnectivityReport) FailingPhasePairs() []PairSummary {
+	return failingPairs(c.PhasePairs)
+}
+
+// Write will write the report to w in the given output format, one of table,
+// json or yaml. The table format only contains failing probes.
+func (c *ConnectivityReport) Write(w io.Writer, output string) error {
+	if output == "json" || output == "yaml" {
+		return writeReport(w, output, c)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "SOURCE PHASE\tDESTINATION PHASE\tFAILED\tTOTAL")
+	for _, p := range c.PhasePairs {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", p.Source, p.Destination, p.Failed, p.Total)
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "SOURCE NODE\tDESTINATION NODE\tFAILED\tTOTAL")
+	for _, p := range c.FailingNodePairs() {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", p.Source, p.Destination, p.Failed, p.Total)
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "SOURCE POD\tSOURCE CNI\tDESTINATION POD\tDESTINATION CNI\tERROR")
+	for _, r := range c.Results {
+		if r.OK {
+			continue
+		}
+		fmt.Fprintf(tw, "%s (%s)\t%s\t%s (%s)\t%s\t%s\n",
+			r.Source.Pod, r.Source.Node, r.Source.CNI,
+			r.Destination.Pod, r.Destination.Node, r.Destination.CNI, r.Error)
+	}
+
+	return tw.Flush()
+}
+
+// WriteFile will write the report to the file at path, as YAML if the path
+// has a .yaml or .yml extension, otherwise as JSON.
+func (c *ConnectivityReport) WriteFile(path string) error {
+	return writeReportFile(path, c)
+}
+
+// Write will write the report to w in the given output format, one of table,
+// json or yaml.
+func (p *PrerequisitesReport) Write(w io.Writer, output string) error {
+	if output == "json" || output == "yaml" {
+		return writeReport(w, output, p)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "CHECK\tNODE\tRESULT\tMESSAGE")
+	for _, r := range p.Results {
+		result := "pass"
+		if !r.Passed {
+			result = "FAIL"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Check, r.Node, result, r.Message)
+	}
+
+	return tw.Flush()
+}
+
+// WriteFile will write the report to the file at path, as YAML if the path
+// has a .yaml or .yml extension, otherwise as JSON.
+func (p *PrerequisitesReport) WriteFile(path string) error {
+	return writeReportFile(path, p)
+}
+
+// writeReport will write the report to w as json or yaml.
+func writeReport(w io.Writer, output string, report interface{}) error {
+	if output == "yaml" {
+		data, err := yaml.Marshal(report)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// writeReportFile will write the report to the file at path, as YAML if the
+// path has a .yaml or .yml extension, otherwise as JSON.
+func writeReportFile(path string, report interface{}) error {
+	var buf bytes.Buffer
+
+	output := "json"
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		output = "yaml"
+	}
+
+	if err := writeReport(&buf, output, report); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func sortedPairs(pairs map[[2]string]*PairSummary) []PairSummary {
+	var sorted []PairSummary
+	for _, p := range pairs {
+		sorted = append(sorted, *p)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Source != sorted[j].Source {
+			return sorted[i].Source < sorted[j].Source
+		}
+		return sorted[i].Destination < sorted[j].Destination
+	})
+
+	return sorted
+}
+
+func failingPairs(pairs []PairSummary) []PairSummary {
+	var failing []PairSummary
+	for _, p := range pairs {
+		if p.Failed > 0 {
+			failing = append(failing, p)
+		}
+	}
+	return failing
+}
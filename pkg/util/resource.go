@@ -2,73 +2,216 @@ package util
 
 import (
 	"context"
-	"io"
-	"os"
-	"os/exec"
+	"fmt"
 
 	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+
+	"github.com/jetstack/cni-migration/pkg/config"
+)
+
+const (
+	// FieldManager is the field manager used for all server-side applies.
+	FieldManager = "cni-migration"
 )
 
 type Factory struct {
 	ctx context.Context
 
 	log    *logrus.Entry
-	client *kubernetes.Clientset
+	config *config.Config
+	client kubernetes.Interface
+
+	// node is the node being operated on, if any.
+	node string
 }
 
-func New(ctx context.Context, log *logrus.Entry, client *kubernetes.Clientset) *Factory {
+func New(ctx context.Context, log *logrus.Entry, config *config.Config) *Factory {
 	return &Factory{
 		ctx:    ctx,
 		log:    log,
-		client: client,
+		config: config,
+		client: config.Client,
 	}
 }
 
-func (f *Factory) CreateDaemonSet(filePath, namespace, name string) error {
-	if err := f.createResource(filePath, namespace, name); err != nil {
+// CreateResource will apply the manifest bundle at filePath, and wait for all
+// DaemonSets, Deployments and StatefulSets in the bundle to become ready.
+func (f *Factory) CreateResource(dryrun bool, filePath, namespace string) error {
+	if err := f.ApplyResource(dryrun, filePath, namespace); err != nil {
 		return err
 	}
 
-	if err := f.WaitDaemonSetReady(namespace, name); err != nil {
+	if dryrun {
+		return nil
+	}
+
+	objs, err := f.config.ReadBundle(filePath)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	resources := &config.Resources{
+		DaemonSets:   make(map[string][]string),
+		Deployments:  make(map[string][]string),
+		StatefulSets: make(map[string][]string),
+	}
+
+	for _, obj := range objs {
+		ns := obj.GetNamespace()
+		if ns == "" {
+			ns = namespace
+		}
+
+		switch obj.GetKind() {
+		case "DaemonSet":
+			resources.DaemonSets[ns] = append(resources.DaemonSets[ns], obj.GetName())
+		case "Deployment":
+			resources.Deployments[ns] = append(resources.Deployments[ns], obj.GetName())
+		case "StatefulSet":
+			resources.StatefulSets[ns] = append(resources.StatefulSets[ns], obj.GetName())
+		}
+	}
+
+	return f.WaitAllReady(resources)
 }
 
-func (f *Factory) createResource(filePath, namespace, name string) error {
-	f.log.Debugf("applying %s: %s", name, filePath)
+// ApplyResource will server-side apply all objects in the manifest bundle at
+// filePath. Namespaced objects without a namespace are applied to namespace.
+func (f *Factory) ApplyResource(dryrun bool, filePath, namespace string) error {
+	f.log.Debugf("applying %s", filePath)
 
-	args := []string{"kubectl", "apply", "--namespace", namespace, "-f", filePath}
-	if err := f.RunCommand(nil, args...); err != nil {
+	objs, err := f.config.ReadBundle(filePath)
+	if err != nil {
 		return err
 	}
 
+	// Namespaces which will be created by this bundle. During a dry run,
+	// objects in these namespaces cannot be applied since the namespace will
+	// not yet exist.
+	bundleNamespaces := make(map[string]bool)
+
+	for _, obj := range objs {
+		client, ns, err := f.resourceClient(obj, namespace)
+		if err != nil {
+			return err
+		}
+
+		if obj.GetKind() == "Namespace" {
+			bundleNamespaces[obj.GetName()] = true
+		}
+
+		if err := f.applyObject(dryrun, client, obj, ns); err != nil {
+			if dryrun && apierrors.IsNotFound(err) && bundleNamespaces[ns] {
+				f.log.Infof("%s %s would be created in new namespace %s",
+					obj.GetKind(), obj.GetName(), ns)
+				continue
+			}
+
+			return fmt.Errorf("failed to apply %s %s from %s: %s",
+				obj.GetKind(), objectName(obj, ns), filePath, err)
+		}
+	}
+
 	return nil
 }
 
-func (f *Factory) DeleteResource(filePath, namespace string) error {
-	f.log.Debugf("deleting %s", filePath)
+func (f *Factory) applyObject(dryrun bool, client dynamic.ResourceInterface, obj *unstructured.Unstructured, namespace string) error {
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	force := true
+	opts := metav1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &force,
+	}
+	if dryrun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
 
-	args := []string{"kubectl", "delete", "--namespace", namespace, "-f", filePath}
-	if err := f.RunCommand(nil, args...); err != nil {
+	if _, err := client.Patch(f.ctx, obj.GetName(), types.ApplyPatchType, data, opts); err != nil {
 		return err
 	}
 
+	f.log.Infof("%s %s applied", obj.GetKind(), objectName(obj, namespace))
+
 	return nil
 }
 
-func (f *Factory) RunCommand(stdout io.Writer, args ...string) error {
-	f.log.Debugf("%s", args)
+// DeleteResource will delete all objects in the manifest bundle at filePath,
+// in reverse order. Objects which do not exist are skipped.
+func (f *Factory) DeleteResource(dryrun bool, filePath, namespace string) error {
+	f.log.Debugf("deleting %s", filePath)
 
-	cmd := exec.Command(args[0], args[1:]...)
-	cmd.Stdout = stdout
-	cmd.Stderr = os.Stderr
-	err := cmd.Run()
+	objs, err := f.config.ReadBundle(filePath)
 	if err != nil {
 		return err
 	}
 
+	opts := metav1.DeleteOptions{}
+	if dryrun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	for i := len(objs) - 1; i >= 0; i-- {
+		obj := objs[i]
+
+		client, ns, err := f.resourceClient(obj, namespace)
+		if err != nil {
+			return err
+		}
+
+		err = client.Delete(f.ctx, obj.GetName(), opts)
+		if apierrors.IsNotFound(err) {
+			f.log.Debugf("%s %s not found", obj.GetKind(), objectName(obj, ns))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete %s %s from %s: %s",
+				obj.GetKind(), objectName(obj, ns), filePath, err)
+		}
+
+		f.log.Infof("%s %s deleted", obj.GetKind(), objectName(obj, ns))
+	}
+
 	return nil
 }
+
+// resourceClient returns a dynamic client for the object's resource, along
+// with the namespace the object belongs to, if namespaced.
+func (f *Factory) resourceClient(obj *unstructured.Unstructured, namespace string) (dynamic.ResourceInterface, string, error) {
+	gvk := obj.GroupVersionKind()
+
+	mapping, err := f.config.RESTMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find resource for %s: %s", gvk, err)
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return f.config.DynamicClient.Resource(mapping.Resource), "", nil
+	}
+
+	ns := obj.GetNamespace()
+	if ns == "" {
+		ns = namespace
+		obj.SetNamespace(ns)
+	}
+
+	return f.config.DynamicClient.Resource(mapping.Resource).Namespace(ns), ns, nil
+}
+
+func objectName(obj *unstructured.Unstructured, namespace string) string {
+	if namespace == "" {
+		return obj.GetName()
+	}
+
+	return namespace + "/" + obj.GetName()
+}
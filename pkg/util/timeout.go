@@ -0,0 +1,50 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithTimeout will run fn with a context which is cancelled after timeout. A
+// zero timeout runs fn with ctx unchanged. If the deadline is exceeded, the
+// returned error names the operation which timed out.
+func WithTimeout(ctx context.Context, timeout time.Duration, operation string, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := fn(ctx)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s timed out after %s: %s", operation, timeout, err)
+	}
+
+	return err
+}
+
+// ForNode returns a copy of the factory using ctx, for operations on the given
+// node. Timeout errors of the returned factory name the node.
+func (f *Factory) ForNode(ctx context.Context, nodeName string) *Factory {
+	nf := *f
+	nf.ctx = ctx
+	nf.node = nodeName
+	nf.log = f.log.WithField("node", nodeName)
+	return &nf
+}
+
+// withTimeout will run fn with a copy of the factory, whose context is
+// cancelled after timeout.
+func (f *Factory) withTimeout(timeout time.Duration, operation string, fn func(f *Factory) error) error {
+	if len(f.node) > 0 {
+		operation = fmt.Sprintf("%s on node %s", operation, f.node)
+	}
+
+	return WithTimeout(f.ctx, timeout, operation, func(ctx context.Context) error {
+		tf := *f
+		tf.ctx = ctx
+		return fn(&tf)
+	})
+}
@@ -1,9 +1,28 @@
 package util
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+
 	"github.com/jetstack/cni-migration/pkg/config"
 )
 
+// readyFunc returns whether the object has finished rolling out, along with a
+// human readable status of its progress.
+type readyFunc func(obj runtime.Object) (string, bool, error)
+
+// WaitAllReady will wait for all of the resources to become ready, each
+// bounded by its readiness timeout.
 func (f *Factory) WaitAllReady(resources *config.Resources) error {
 	for namespace, names := range resources.Deployments {
 		for _, name := range names {
@@ -32,25 +51,215 @@ func (f *Factory) WaitAllReady(resources *config.Resources) error {
 	return nil
 }
 
-// waitDeploymentReadynamespace will wait for a all pods in a Deployment to become ready
+// waitDeploymentReady will wait for a all pods in a Deployment to become ready
 func (f *Factory) waitDeploymentReady(namespace, name string) error {
-	return f.waitReady("deployment", name, namespace)
+	client := f.client.AppsV1().Deployments(namespace)
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = nameSelector(name)
+			return client.List(f.ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = nameSelector(name)
+			return client.Watch(f.ctx, options)
+		},
+	}
+
+	return f.waitReady("deployment", namespace, name, lw, &appsv1.Deployment{}, deploymentReady)
 }
 
-// waitDaemonSetReadynamespace will wait for a all pods in a DaemonSet to become ready
+// WaitDaemonSetReady will wait for a all pods in a DaemonSet to become ready,
+// bounded by its readiness timeout.
 func (f *Factory) WaitDaemonSetReady(namespace, name string) error {
-	return f.waitReady("daemonset", name, namespace)
+	client := f.client.AppsV1().DaemonSets(namespace)
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = nameSelector(name)
+			return client.List(f.ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = nameSelector(name)
+			return client.Watch(f.ctx, options)
+		},
+	}
+
+	return f.waitReady("daemonset", namespace, name, lw, &appsv1.DaemonSet{}, daemonSetReady)
 }
 
-// waitStatefulSetReadynamespace will wait for a all pods in a StatefulSet to become ready
+// waitStatefulSetReady will wait for a all pods in a StatefulSet to become ready
 func (f *Factory) waitStatefulSetReady(namespace, name string) error {
-	return f.waitReady("statefulset", name, namespace)
+	client := f.client.AppsV1().StatefulSets(namespace)
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = nameSelector(name)
+			return client.List(f.ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = nameSelector(name)
+			return client.Watch(f.ctx, options)
+		},
+	}
+
+	return f.waitReady("statefulset", namespace, name, lw, &appsv1.StatefulSet{}, statefulSetReady)
 }
 
-func (f *Factory) waitReady(kind, name, namespace string) error {
-	args := []string{"kubectl", "rollout", "status", kind, "--namespace", namespace, name}
-	if err := f.RunCommand(nil, args...); err != nil {
+// waitReady will watch the resource until ready reports that the rollout is
+// complete, the context is cancelled, or the readiness timeout expires.
+func (f *Factory) waitReady(kind, namespace, name string, lw cache.ListerWatcher, objType runtime.Object, ready readyFunc) error {
+	ctx := f.ctx
+	if timeout := f.config.Readiness.TimeoutFor(kind, namespace, name); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(f.ctx, timeout)
+		defer cancel()
+	}
+
+	f.log.Debugf("waiting for %s %s/%s to become ready", kind, namespace, name)
+
+	precondition := func(store cache.Store) (bool, error) {
+		if len(store.List()) == 0 {
+			return false, fmt.Errorf("%s %s/%s not found", kind, namespace, name)
+		}
+		return false, nil
+	}
+
+	var lastStatus string
+	_, err := watchtools.UntilWithSync(ctx, lw, objType, precondition, func(event watch.Event) (bool, error) {
+		switch event.Type {
+		case watch.Deleted:
+			return false, fmt.Errorf("%s %s/%s was deleted", kind, namespace, name)
+		case watch.Error:
+			return false, fmt.Errorf("error watching %s %s/%s: %v", kind, namespace, name, event.Object)
+		}
+
+		status, done, err := ready(event.Object)
+		if err != nil {
+			return false, fmt.Errorf("%s %s/%s: %s", kind, namespace, name, err)
+		}
+
+		if !done && status != lastStatus {
+			f.log.Debugf("%s %s/%s: %s", kind, namespace, name, status)
+		}
+		lastStatus = status
+
+		return done, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for %s %s/%s to become ready (%s): %s",
+			kind, namespace, name, strings.TrimSpace(lastStatus), ctx.Err())
+	}
+	if err != nil {
 		return err
 	}
+
 	return nil
 }
+
+func nameSelector(name string) string {
+	return fields.OneTermEqualSelector("metadata.name", name).String()
+}
+
+func daemonSetReady(obj runtime.Object) (string, bool, error) {
+	ds, ok := obj.(*appsv1.DaemonSet)
+	if !ok {
+		return "", false, fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	if ds.Generation > ds.Status.ObservedGeneration {
+		return "waiting for spec update to be observed", false, nil
+	}
+
+	desired := ds.Status.DesiredNumberScheduled
+
+	if ds.Spec.UpdateStrategy.Type == appsv1.RollingUpdateDaemonSetStrategyType &&
+		ds.Status.UpdatedNumberScheduled < desired {
+		return fmt.Sprintf("%d out of %d new pods have been updated",
+			ds.Status.UpdatedNumberScheduled, desired), false, nil
+	}
+
+	if ds.Status.NumberReady < desired {
+		return fmt.Sprintf("%d out of %d pods are ready",
+			ds.Status.NumberReady, desired), false, nil
+	}
+
+	return "", true, nil
+}
+
+func deploymentReady(obj runtime.Object) (string, bool, error) {
+	deploy, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return "", false, fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	if deploy.Generation > deploy.Status.ObservedGeneration {
+		return "waiting for spec update to be observed", false, nil
+	}
+
+	for _, cond := range deploy.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return "", false, fmt.Errorf("rollout exceeded its progress deadline: %s", cond.Message)
+		}
+	}
+
+	var replicas int32 = 1
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+
+	if deploy.Status.UpdatedReplicas < replicas {
+		return fmt.Sprintf("%d out of %d new replicas have been updated",
+			deploy.Status.UpdatedReplicas, replicas), false, nil
+	}
+
+	if deploy.Status.Replicas > deploy.Status.UpdatedReplicas {
+		return fmt.Sprintf("%d old replicas are pending termination",
+			deploy.Status.Replicas-deploy.Status.UpdatedReplicas), false, nil
+	}
+
+	if deploy.Status.AvailableReplicas < deploy.Status.UpdatedReplicas {
+		return fmt.Sprintf("%d of %d updated replicas are available",
+			deploy.Status.AvailableReplicas, deploy.Status.UpdatedReplicas), false, nil
+	}
+
+	return "", true, nil
+}
+
+func statefulSetReady(obj runtime.Object) (string, bool, error) {
+	sts, ok := obj.(*appsv1.StatefulSet)
+	if !ok {
+		return "", false, fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	if sts.Generation > sts.Status.ObservedGeneration {
+		return "waiting for spec update to be observed", false, nil
+	}
+
+	var replicas int32 = 1
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	if sts.Status.ReadyReplicas < replicas {
+		return fmt.Sprintf("%d out of %d pods are ready",
+			sts.Status.ReadyReplicas, replicas), false, nil
+	}
+
+	if sts.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return "", true, nil
+	}
+
+	if ru := sts.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil {
+		if sts.Status.UpdatedReplicas < replicas-*ru.Partition {
+			return fmt.Sprintf("%d out of %d new pods have been updated",
+				sts.Status.UpdatedReplicas, replicas-*ru.Partition), false, nil
+		}
+
+		return "", true, nil
+	}
+
+	if sts.Status.UpdateRevision != sts.Status.CurrentRevision {
+		return fmt.Sprintf("%d out of %d new pods have been updated",
+			sts.Status.UpdatedReplicas, replicas), false, nil
+	}
+
+	return "", true, nil
+}
@@ -0,0 +1,4 @@
+package version
+
+// Version is the version of cni-migration, set at build time.
+var Version = "devel"